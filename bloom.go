@@ -0,0 +1,51 @@
+package main
+
+import "hash/fnv"
+
+// bloomFilter is a small, fixed-size Bloom filter used as a fast negative
+// check before falling back to slower, exact lookups: if MightContain
+// returns false, the key has definitely never been added.
+type bloomFilter struct {
+	bits []uint64
+	k    int
+}
+
+func newBloomFilter(bits, k int) *bloomFilter {
+	return &bloomFilter{bits: make([]uint64, (bits+63)/64), k: k}
+}
+
+// offsets computes k bit positions for key using double hashing, so only
+// two real hash computations are needed regardless of k.
+func (b *bloomFilter) offsets(key string) []uint64 {
+	h1 := fnv.New64a()
+	h1.Write([]byte(key))
+	base := h1.Sum64()
+
+	h2 := fnv.New64()
+	h2.Write([]byte(key))
+	step := h2.Sum64()
+
+	n := uint64(len(b.bits) * 64)
+	offsets := make([]uint64, b.k)
+	for i := 0; i < b.k; i++ {
+		offsets[i] = (base + uint64(i)*step) % n
+	}
+	return offsets
+}
+
+func (b *bloomFilter) Add(key string) {
+	for _, offset := range b.offsets(key) {
+		b.bits[offset/64] |= 1 << (offset % 64)
+	}
+}
+
+// MightContain reports whether key may have been added before. A false
+// result is certain; a true result may be a false positive.
+func (b *bloomFilter) MightContain(key string) bool {
+	for _, offset := range b.offsets(key) {
+		if b.bits[offset/64]&(1<<(offset%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -9,29 +9,70 @@ import (
 	"strings"
 )
 
-type Rows struct {
-	Source     *csv.Reader
-	Headers    []string
-	CurrentRow []string
-	Err        error
+// Rows is a minimal streaming abstraction over a table's data. It is
+// implemented by each Repo backend (CSV files, SQL result sets, in-memory
+// fixtures, ...), letting Validate run unchanged regardless of where the
+// data comes from.
+type Rows interface {
+	// Headers returns the table's column names, in order.
+	Headers() []string
+
+	// Next advances to the next row, returning false once the rows are
+	// exhausted or an error occurs. Err reports the error, if any.
+	Next() bool
+
+	// Row returns the current row. It is only valid after a call to Next
+	// that returned true.
+	Row() []string
+
+	// Err returns the first error encountered while reading, if any.
+	Err() error
+}
+
+type csvRows struct {
+	source     *csv.Reader
+	headers    []string
+	currentRow []string
+	err        error
+}
+
+func (r *csvRows) Headers() []string { return r.headers }
+func (r *csvRows) Row() []string     { return r.currentRow }
+
+func (r *csvRows) Err() error {
+	if r.err == io.EOF {
+		return nil
+	}
+	return r.err
 }
 
-func (r *Rows) Next() bool {
-	if r.Err != nil {
+func (r *csvRows) Next() bool {
+	if r.err != nil {
 		return false
 	}
-	r.CurrentRow, r.Err = r.Source.Read()
-	return r.Err == nil
+	r.currentRow, r.err = r.source.Read()
+	return r.err == nil
 }
 
-func OpenTable(table *csv.Reader) Rows {
-	rows := Rows{Source: table}
-	rows.Headers, rows.Err = rows.Source.Read()
+func openCSVTable(table *csv.Reader) *csvRows {
+	rows := &csvRows{source: table}
+	rows.headers, rows.err = rows.source.Read()
 	return rows
 }
 
+// Repo abstracts over where a schema's tables live: a directory of CSVs, a
+// SQL database, in-memory fixture data, or several of these fanned out
+// together.
 type Repo interface {
 	WithTable(table string, f func(r Rows) error) error
+
+	// WithJoin streams the rows of local and foreign that match under fkm,
+	// joining them on the foreign key's columns.
+	WithJoin(
+		local, foreign TableName,
+		fkm ForeignKeyMapping,
+		f func(localRow, foreignRow []string) error,
+	) error
 }
 
 type FileSystemRepo struct {
@@ -49,18 +90,26 @@ func (fsr FileSystemRepo) WithTable(table string, f func(r Rows) error) error {
 	}
 	defer file.Close()
 
-	rows := OpenTable(csv.NewReader(file))
-	if rows.Err != nil {
-		return rows.Err
+	rows := openCSVTable(csv.NewReader(file))
+	if rows.err != nil {
+		return rows.err
 	}
 
 	if err := f(rows); err != nil {
 		return err
 	}
 
-	if rows.Err != nil && rows.Err != io.EOF {
-		return rows.Err
+	if rows.err != nil && rows.err != io.EOF {
+		return rows.err
 	}
 
 	return nil
 }
+
+func (fsr FileSystemRepo) WithJoin(
+	local, foreign TableName,
+	fkm ForeignKeyMapping,
+	f func(localRow, foreignRow []string) error,
+) error {
+	return hashJoin(fsr, local, foreign, fkm, f)
+}
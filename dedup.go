@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+// diskSetBatchSize bounds how many keys diskSet holds in memory before
+// spilling them to disk as a sorted run.
+const diskSetBatchSize = 100000
+
+// initialBloomBits is the Bloom filter's starting size. It grows (see
+// growBloom) as the set's cardinality outgrows it, so diskSet stays
+// accurate regardless of how many keys it ends up holding.
+const initialBloomBits = 1 << 17
+
+// diskSet is a memory-bounded replacement for a nested-map Set, used to
+// detect duplicate primary-key/unique-constraint tuples without holding
+// every key seen so far in RAM. A Bloom filter rejects the overwhelming
+// majority of non-duplicate keys without touching disk; only keys the
+// filter can't rule out fall back to a binary search over the on-disk
+// sorted runs earlier batches were spilled to, so a 100M-row CSV can be
+// validated in bounded memory without degrading into a linear scan per
+// lookup.
+type diskSet struct {
+	bloom *bloomFilter
+	bits  int
+	count int
+
+	buffer []string
+	runs   []string
+}
+
+func newDiskSet() *diskSet {
+	return &diskSet{bloom: newBloomFilter(initialBloomBits, 4), bits: initialBloomBits}
+}
+
+// Put records key as seen and reports whether it had already been seen.
+func (s *diskSet) Put(key string) (bool, error) {
+	if s.bloom.MightContain(key) {
+		duplicate, err := s.seen(key)
+		if err != nil || duplicate {
+			return duplicate, err
+		}
+	}
+
+	s.bloom.Add(key)
+	s.count++
+	s.buffer = append(s.buffer, key)
+	if len(s.buffer) >= diskSetBatchSize {
+		if err := s.flush(); err != nil {
+			return false, err
+		}
+	}
+	if s.count >= bloomCapacity(s.bits) {
+		if err := s.growBloom(); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// Contains reports whether key was ever recorded via Put, without adding
+// it. Foreign key checks use this to test a child row's key against the
+// parent table's diskSet, built while the parent was validated.
+func (s *diskSet) Contains(key string) (bool, error) {
+	if !s.bloom.MightContain(key) {
+		return false, nil
+	}
+	return s.seen(key)
+}
+
+// seen checks key against the buffered keys and every on-disk run. It is
+// only reached when the Bloom filter can't already rule key out.
+func (s *diskSet) seen(key string) (bool, error) {
+	for _, buffered := range s.buffer {
+		if buffered == key {
+			return true, nil
+		}
+	}
+	for _, run := range s.runs {
+		found, err := runContains(run, key)
+		if err != nil {
+			return false, err
+		}
+		if found {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// bloomCapacity is the cardinality at which a Bloom filter with bits bits
+// and 4 hash functions should be grown: comfortably below the ~bits*ln2/4
+// load that's optimal for that configuration, so MightContain's
+// false-positive rate stays low as the set grows.
+func bloomCapacity(bits int) int {
+	return bits / 6
+}
+
+// growBloom doubles the Bloom filter's bit array and replays every key
+// seen so far (buffered and on-disk) through it. This is the one place
+// diskSet pays for its memory bound with a full pass over every run, but
+// doubling means it happens only O(log n) times over the set's lifetime.
+func (s *diskSet) growBloom() error {
+	s.bits *= 2
+	s.bloom = newBloomFilter(s.bits, 4)
+	for _, key := range s.buffer {
+		s.bloom.Add(key)
+	}
+	for _, run := range s.runs {
+		if err := eachRunKey(run, s.bloom.Add); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func eachRunKey(path string, f func(string)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		f(scanner.Text())
+	}
+	return scanner.Err()
+}
+
+// flush sorts the in-memory buffer and writes it out as a new on-disk run,
+// bounding diskSet's memory use regardless of input size.
+func (s *diskSet) flush() error {
+	if len(s.buffer) == 0 {
+		return nil
+	}
+	sort.Strings(s.buffer)
+
+	file, err := ioutil.TempFile("", "csv-schema-dedup-*.run")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	for _, key := range s.buffer {
+		if _, err := w.WriteString(key + "\n"); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	s.runs = append(s.runs, file.Name())
+	s.buffer = s.buffer[:0]
+	return nil
+}
+
+// Close removes every on-disk run diskSet spilled while tracking keys.
+func (s *diskSet) Close() error {
+	for _, run := range s.runs {
+		if err := os.Remove(run); err != nil {
+			return err
+		}
+	}
+	s.runs = nil
+	return nil
+}
+
+// runContains binary-searches the sorted run file at path for key,
+// reading only a handful of lines near the candidate offsets rather than
+// scanning the whole file.
+func runContains(path, key string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	lo, hi := int64(0), info.Size()
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		lineStart, err := seekLineStart(file, mid)
+		if err != nil {
+			return false, err
+		}
+		line, err := readLine(file, lineStart)
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case line == key:
+			return true, nil
+		case line < key:
+			lo = lineStart + int64(len(line)) + 1
+		default:
+			hi = lineStart
+		}
+	}
+	return false, nil
+}
+
+// seekLineStart finds the offset of the start of the line containing byte
+// offset pos, by scanning backward a byte at a time for the preceding
+// newline (or the start of the file).
+func seekLineStart(file *os.File, pos int64) (int64, error) {
+	buf := make([]byte, 1)
+	for pos > 0 {
+		if _, err := file.ReadAt(buf, pos-1); err != nil {
+			return 0, err
+		}
+		if buf[0] == '\n' {
+			break
+		}
+		pos--
+	}
+	return pos, nil
+}
+
+// readLine reads the newline-terminated line starting at offset.
+func readLine(file *os.File, offset int64) (string, error) {
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return "", err
+	}
+	line, err := bufio.NewReader(file).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\n"), nil
+}
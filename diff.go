@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+)
+
+// MigrationOpKind classifies the structural change a MigrationOp describes.
+type MigrationOpKind string
+
+const (
+	OpAddTable            MigrationOpKind = "add_table"
+	OpDropTable           MigrationOpKind = "drop_table"
+	OpAddColumn           MigrationOpKind = "add_column"
+	OpDropColumn          MigrationOpKind = "drop_column"
+	OpChangeColumnType    MigrationOpKind = "change_column_type"
+	OpAddForeignKey       MigrationOpKind = "add_foreign_key"
+	OpDropForeignKey      MigrationOpKind = "drop_foreign_key"
+	OpAddUniqueConstraint MigrationOpKind = "add_unique_constraint"
+)
+
+// MigrationOp is a single structural change between two schema versions.
+// Only the fields relevant to Kind are populated.
+type MigrationOp struct {
+	Kind MigrationOpKind
+
+	Table TableSpec // OpAddTable, OpDropTable
+
+	TableName TableName   // every other op
+	Column    ColumnSpec  // OpAddColumn, OpDropColumn
+	OldType   DataType    // OpChangeColumnType
+	NewType   DataType    // OpChangeColumnType
+
+	ForeignKey ForeignKeyMapping // OpAddForeignKey, OpDropForeignKey
+
+	UniqueColumn Column // OpAddUniqueConstraint
+}
+
+func (op MigrationOp) String() string {
+	switch op.Kind {
+	case OpAddTable:
+		return fmt.Sprintf("add table '%s'", op.Table.Name)
+	case OpDropTable:
+		return fmt.Sprintf("drop table '%s'", op.Table.Name)
+	case OpAddColumn:
+		return fmt.Sprintf(
+			"add column '%s'.'%s' (%s)",
+			op.TableName,
+			op.Column.Name,
+			op.Column.Type,
+		)
+	case OpDropColumn:
+		return fmt.Sprintf(
+			"drop column '%s'.'%s'",
+			op.TableName,
+			op.Column.Name,
+		)
+	case OpChangeColumnType:
+		return fmt.Sprintf(
+			"change column '%s'.'%s' type from %s to %s",
+			op.TableName,
+			op.Column.Name,
+			op.OldType,
+			op.NewType,
+		)
+	case OpAddForeignKey:
+		return fmt.Sprintf(
+			"add foreign key '%s'.%s -> '%s'.%s",
+			op.TableName,
+			op.ForeignKey.LocalColumn,
+			op.ForeignKey.ForeignTable,
+			op.ForeignKey.ForeignColumn,
+		)
+	case OpDropForeignKey:
+		return fmt.Sprintf(
+			"drop foreign key '%s'.%s -> '%s'.%s",
+			op.TableName,
+			op.ForeignKey.LocalColumn,
+			op.ForeignKey.ForeignTable,
+			op.ForeignKey.ForeignColumn,
+		)
+	case OpAddUniqueConstraint:
+		return fmt.Sprintf(
+			"add unique constraint on '%s'.%s",
+			op.TableName,
+			op.UniqueColumn,
+		)
+	default:
+		return fmt.Sprintf("unknown migration op: %s", op.Kind)
+	}
+}
+
+// ComputeDiff compares old and new, the schemas for a prior and a target
+// version of the same database, and returns the ordered set of migration
+// operations that would carry the former to the latter: dropped tables
+// first, then added tables, then the column and constraint changes of each
+// table present in both, in which dropped columns and foreign keys come
+// before added ones. A generated migration therefore never adds something
+// before clearing the way for it, and a foreign key added to an existing
+// table comes after the creation of a new table it references.
+func ComputeDiff(old, new []TableSpec) ([]MigrationOp, error) {
+	oldTables := make(map[TableName]TableSpec, len(old))
+	for _, table := range old {
+		oldTables[table.Name] = table
+	}
+	newTables := make(map[TableName]TableSpec, len(new))
+	for _, table := range new {
+		newTables[table.Name] = table
+	}
+
+	var ops []MigrationOp
+
+	for _, table := range old {
+		if _, found := newTables[table.Name]; !found {
+			ops = append(ops, MigrationOp{Kind: OpDropTable, Table: table})
+		}
+	}
+
+	for _, table := range new {
+		if _, found := oldTables[table.Name]; !found {
+			ops = append(ops, MigrationOp{Kind: OpAddTable, Table: table})
+		}
+	}
+
+	for _, table := range old {
+		newTable, found := newTables[table.Name]
+		if !found {
+			continue
+		}
+		ops = append(ops, diffTable(table, newTable)...)
+	}
+
+	return ops, nil
+}
+
+func diffTable(old, new TableSpec) []MigrationOp {
+	var ops []MigrationOp
+
+	oldColumns := make(map[ColumnName]ColumnSpec, len(old.Columns))
+	for _, column := range old.Columns {
+		oldColumns[column.Name] = column
+	}
+	newColumns := make(map[ColumnName]ColumnSpec, len(new.Columns))
+	for _, column := range new.Columns {
+		newColumns[column.Name] = column
+	}
+
+	for _, column := range old.Columns {
+		if _, found := newColumns[column.Name]; !found {
+			ops = append(ops, MigrationOp{
+				Kind:      OpDropColumn,
+				TableName: old.Name,
+				Column:    column,
+			})
+		}
+	}
+
+	for _, fkm := range old.ForeignKeys {
+		if !foreignKeyIn(fkm, new.ForeignKeys) {
+			ops = append(ops, MigrationOp{
+				Kind:       OpDropForeignKey,
+				TableName:  old.Name,
+				ForeignKey: fkm,
+			})
+		}
+	}
+
+	for _, column := range new.Columns {
+		oldColumn, found := oldColumns[column.Name]
+		if !found {
+			ops = append(ops, MigrationOp{
+				Kind:      OpAddColumn,
+				TableName: new.Name,
+				Column:    column,
+			})
+			continue
+		}
+		if oldColumn.Type != column.Type {
+			ops = append(ops, MigrationOp{
+				Kind:      OpChangeColumnType,
+				TableName: new.Name,
+				Column:    column,
+				OldType:   oldColumn.Type,
+				NewType:   column.Type,
+			})
+		}
+	}
+
+	for _, column := range new.UniqueColumns {
+		if !uniqueColumnIn(column, old.UniqueColumns) {
+			ops = append(ops, MigrationOp{
+				Kind:         OpAddUniqueConstraint,
+				TableName:    new.Name,
+				UniqueColumn: column,
+			})
+		}
+	}
+
+	for _, fkm := range new.ForeignKeys {
+		if !foreignKeyIn(fkm, old.ForeignKeys) {
+			ops = append(ops, MigrationOp{
+				Kind:       OpAddForeignKey,
+				TableName:  new.Name,
+				ForeignKey: fkm,
+			})
+		}
+	}
+
+	return ops
+}
+
+func foreignKeyIn(fkm ForeignKeyMapping, fkms []ForeignKeyMapping) bool {
+	for _, candidate := range fkms {
+		if candidate.LocalColumn.Equal(fkm.LocalColumn) &&
+			candidate.ForeignTable == fkm.ForeignTable &&
+			candidate.ForeignColumn.Equal(fkm.ForeignColumn) {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueColumnIn(column Column, columns []Column) bool {
+	for _, candidate := range columns {
+		if candidate.Equal(column) {
+			return true
+		}
+	}
+	return false
+}
+
+// RenderMetaschemaJSON renders schema, the target state of a migration, as
+// the JSON []TableSpec representation that ParseSchema/Validate consume.
+func RenderMetaschemaJSON(schema []TableSpec) ([]byte, error) {
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// RenderMetaschemaCSV renders schema as a schema.csv metaschema file, in the
+// same column order the schema loader expects.
+func RenderMetaschemaCSV(schema []TableSpec) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+
+	if err := w.Write([]string{
+		"table", "column", "not_null", "unique", "primary_key", "type",
+		"null", "references_table", "references_column",
+	}); err != nil {
+		return nil, err
+	}
+
+	for _, table := range schema {
+		unique := map[ColumnName]bool{}
+		for _, column := range table.UniqueColumns {
+			unique[column.Name] = true
+		}
+		pkey := map[ColumnName]bool{}
+		for c := table.PrimaryKey; c != nil; c = c.Tail {
+			pkey[c.Name] = true
+		}
+		refs := map[ColumnName]ForeignKeyMapping{}
+		for _, fkm := range table.ForeignKeys {
+			refs[fkm.LocalColumn.Name] = fkm
+		}
+
+		for _, column := range table.Columns {
+			referencesTable, referencesColumn := "", ""
+			if fkm, found := refs[column.Name]; found {
+				referencesTable = string(fkm.ForeignTable)
+				referencesColumn = string(fkm.ForeignColumn.Name)
+			}
+			if err := w.Write([]string{
+				string(table.Name),
+				string(column.Name),
+				formatBool(column.NotNull),
+				formatBool(unique[column.Name]),
+				formatBool(pkey[column.Name]),
+				string(column.Type),
+				column.Null,
+				referencesTable,
+				referencesColumn,
+			}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func formatBool(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+)
+
+func opKinds(ops []MigrationOp) map[MigrationOpKind]int {
+	counts := map[MigrationOpKind]int{}
+	for _, op := range ops {
+		counts[op.Kind]++
+	}
+	return counts
+}
+
+func TestComputeDiffAddAndDropTable(t *testing.T) {
+	old := []TableSpec{{Name: "a"}}
+	new := []TableSpec{{Name: "b"}}
+
+	ops, err := ComputeDiff(old, new)
+	if err != nil {
+		t.Fatalf("ComputeDiff: %v", err)
+	}
+
+	counts := opKinds(ops)
+	if counts[OpDropTable] != 1 || counts[OpAddTable] != 1 {
+		t.Fatalf("ops = %v, want one OpDropTable and one OpAddTable", ops)
+	}
+	// Drops must precede adds so a rename-by-drop-and-add never tries to
+	// add before the old table is out of the way.
+	if ops[0].Kind != OpDropTable {
+		t.Fatalf("ops[0].Kind = %s, want %s", ops[0].Kind, OpDropTable)
+	}
+}
+
+func TestComputeDiffAddDropColumnAndChangeType(t *testing.T) {
+	old := []TableSpec{{
+		Name: "t",
+		Columns: []ColumnSpec{
+			{Name: "old", Type: DataTypeString},
+			{Name: "same", Type: DataTypeInt},
+		},
+	}}
+	new := []TableSpec{{
+		Name: "t",
+		Columns: []ColumnSpec{
+			{Name: "same", Type: DataTypeFloat},
+			{Name: "new", Type: DataTypeString},
+		},
+	}}
+
+	ops, err := ComputeDiff(old, new)
+	if err != nil {
+		t.Fatalf("ComputeDiff: %v", err)
+	}
+
+	counts := opKinds(ops)
+	if counts[OpDropColumn] != 1 || counts[OpAddColumn] != 1 || counts[OpChangeColumnType] != 1 {
+		t.Fatalf("ops = %v, want one each of OpDropColumn/OpAddColumn/OpChangeColumnType", ops)
+	}
+}
+
+func TestComputeDiffForeignKeyAndUniqueConstraint(t *testing.T) {
+	fkm := ForeignKeyMapping{
+		LocalColumn:   Column{Name: "customer_id"},
+		ForeignTable:  "customers",
+		ForeignColumn: Column{Name: "id"},
+	}
+	old := []TableSpec{{Name: "orders"}}
+	new := []TableSpec{{
+		Name:          "orders",
+		ForeignKeys:   []ForeignKeyMapping{fkm},
+		UniqueColumns: []Column{{Name: "order_number"}},
+	}}
+
+	ops, err := ComputeDiff(old, new)
+	if err != nil {
+		t.Fatalf("ComputeDiff: %v", err)
+	}
+
+	counts := opKinds(ops)
+	if counts[OpAddForeignKey] != 1 {
+		t.Fatalf("ops = %v, want one OpAddForeignKey", ops)
+	}
+	if counts[OpAddUniqueConstraint] != 1 {
+		t.Fatalf("ops = %v, want one OpAddUniqueConstraint", ops)
+	}
+}
+
+func TestComputeDiffUnchangedSchemaProducesNoOps(t *testing.T) {
+	schema := twoTableSchema()
+	ops, err := ComputeDiff(schema, schema)
+	if err != nil {
+		t.Fatalf("ComputeDiff: %v", err)
+	}
+	if len(ops) != 0 {
+		t.Fatalf("ComputeDiff(schema, schema) = %v, want no ops", ops)
+	}
+}
+
+func TestRenderMetaschemaCSVColumnOrderAndForeignKeys(t *testing.T) {
+	out, err := RenderMetaschemaCSV(twoTableSchema())
+	if err != nil {
+		t.Fatalf("RenderMetaschemaCSV: %v", err)
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatalf("parsing rendered CSV: %v", err)
+	}
+
+	wantHeader := []string{
+		"table", "column", "not_null", "unique", "primary_key", "type",
+		"null", "references_table", "references_column",
+	}
+	if len(records) == 0 {
+		t.Fatal("RenderMetaschemaCSV produced no rows")
+	}
+	got := records[0]
+	if len(got) != len(wantHeader) {
+		t.Fatalf("header = %v, want %v", got, wantHeader)
+	}
+	for i, col := range wantHeader {
+		if got[i] != col {
+			t.Errorf("header[%d] = %q, want %q", i, got[i], col)
+		}
+	}
+
+	var fkRow []string
+	for _, row := range records[1:] {
+		if row[0] == "orders" && row[1] == "customer_id" {
+			fkRow = row
+		}
+	}
+	if fkRow == nil {
+		t.Fatal("no row found for orders.customer_id")
+	}
+	if fkRow[7] != "customers" || fkRow[8] != "id" {
+		t.Fatalf("orders.customer_id row = %v, want references_table=customers references_column=id", fkRow)
+	}
+}
@@ -0,0 +1,516 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// GenerateGo renders schema as a standalone Go source file in package pkg:
+// one struct per table with typed fields, Scan/Row methods for converting
+// to and from CSV cells, a Read<Table> iterator per table, and an accessor
+// method per foreign key for walking to the referenced row. This lets
+// downstream code consume validated CSVs without stringly-typed row
+// indexing, the way sqlc/sqlboiler generate typed accessors from a SQL
+// schema.
+//
+// The generated file imports only the standard library; it has no
+// dependency on this package, so it can be dropped into any project.
+func GenerateGo(pkg string, schema []TableSpec) (string, error) {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "// Code generated by csv-schema gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(buf, "package %s\n\n", pkg)
+	fmt.Fprintf(buf, "import (\n")
+	fmt.Fprintf(buf, "\t\"encoding/csv\"\n")
+	fmt.Fprintf(buf, "\t\"fmt\"\n")
+	fmt.Fprintf(buf, "\t\"io\"\n")
+	if usesStrconvType(schema) {
+		fmt.Fprintf(buf, "\t\"strconv\"\n")
+	}
+	if usesArrayType(schema) {
+		fmt.Fprintf(buf, "\t\"strings\"\n")
+	}
+	if usesTimeType(schema) {
+		fmt.Fprintf(buf, "\t\"time\"\n")
+	}
+	fmt.Fprintf(buf, ")\n")
+
+	for _, table := range schema {
+		if err := genTable(buf, table, schema); err != nil {
+			return "", err
+		}
+	}
+	return buf.String(), nil
+}
+
+func genTable(buf *bytes.Buffer, table TableSpec, schema []TableSpec) error {
+	typeName := goName(string(table.Name))
+
+	fmt.Fprintf(buf, "\n// %s is the generated type for the '%s' table.\n", typeName, table.Name)
+	fmt.Fprintf(buf, "type %s struct {\n", typeName)
+	for _, column := range table.Columns {
+		fieldType, err := goFieldType(column.Type)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(buf, "\t%s %s\n", goName(string(column.Name)), fieldType)
+	}
+	fmt.Fprintf(buf, "}\n")
+
+	if err := genScan(buf, typeName, table); err != nil {
+		return err
+	}
+	genRow(buf, typeName, table)
+	genIter(buf, typeName, table)
+
+	for _, fkm := range table.ForeignKeys {
+		if err := genForeignKeyAccessor(buf, typeName, table, fkm, schema); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// genScan emits the Scan method that populates a *typeName from a CSV row,
+// in schema column order.
+func genScan(buf *bytes.Buffer, typeName string, table TableSpec) error {
+	receiver := strings.ToLower(typeName[:1])
+
+	fmt.Fprintf(buf, "\n// Scan populates %s's fields from row, in schema column order.\n", receiver)
+	fmt.Fprintf(buf, "func (%s *%s) Scan(row []string) error {\n", receiver, typeName)
+	fmt.Fprintf(buf, "\tif len(row) != %d {\n", len(table.Columns))
+	fmt.Fprintf(
+		buf,
+		"\t\treturn fmt.Errorf(\"%s: wrong number of cells; wanted %d, got %%d\", len(row))\n",
+		table.Name,
+		len(table.Columns),
+	)
+	fmt.Fprintf(buf, "\t}\n")
+
+	for i, column := range table.Columns {
+		field := receiver + "." + goName(string(column.Name))
+		expr, err := goScanExpr(column.Type, fmt.Sprintf("row[%d]", i))
+		if err != nil {
+			return err
+		}
+		if expr.simple {
+			fmt.Fprintf(buf, "\t%s = %s\n", field, expr.code)
+			continue
+		}
+		fmt.Fprintf(buf, "\t{\n")
+		fmt.Fprintf(buf, "\t\tv, err := %s\n", expr.code)
+		fmt.Fprintf(buf, "\t\tif err != nil {\n")
+		fmt.Fprintf(
+			buf,
+			"\t\t\treturn fmt.Errorf(\"%s: column '%s': %%v\", err)\n",
+			table.Name,
+			column.Name,
+		)
+		fmt.Fprintf(buf, "\t\t}\n")
+		fmt.Fprintf(buf, "\t\t%s = v\n", field)
+		fmt.Fprintf(buf, "\t}\n")
+	}
+	fmt.Fprintf(buf, "\treturn nil\n")
+	fmt.Fprintf(buf, "}\n")
+	return nil
+}
+
+// genRow emits the Row method that renders a typeName back into CSV cells,
+// the inverse of Scan.
+func genRow(buf *bytes.Buffer, typeName string, table TableSpec) {
+	receiver := strings.ToLower(typeName[:1])
+
+	fmt.Fprintf(buf, "\n// Row renders %s back into CSV cells, in schema column order.\n", receiver)
+	fmt.Fprintf(buf, "func (%s %s) Row() []string {\n", receiver, typeName)
+	fmt.Fprintf(buf, "\treturn []string{\n")
+	for _, column := range table.Columns {
+		field := receiver + "." + goName(string(column.Name))
+		fmt.Fprintf(buf, "\t\t%s,\n", goRowExpr(column.Type, field))
+	}
+	fmt.Fprintf(buf, "\t}\n")
+	fmt.Fprintf(buf, "}\n")
+}
+
+// genIter emits a Read<Table> constructor and a <Table>Iter type for
+// streaming typed rows out of an arbitrary io.Reader.
+func genIter(buf *bytes.Buffer, typeName string, table TableSpec) {
+	iterName := typeName + "Iter"
+	readerName := "Read" + typeName
+
+	fmt.Fprintf(buf, "\n// %s streams %s values out of a CSV.\n", iterName, typeName)
+	fmt.Fprintf(buf, "type %s struct {\n", iterName)
+	fmt.Fprintf(buf, "\tsource *csv.Reader\n")
+	fmt.Fprintf(buf, "\terr    error\n")
+	fmt.Fprintf(buf, "}\n")
+
+	fmt.Fprintf(
+		buf,
+		"\n// %s checks r's header against the '%s' schema and returns an "+
+			"iterator over its rows.\n",
+		readerName,
+		table.Name,
+	)
+	fmt.Fprintf(buf, "func %s(r io.Reader) (*%s, error) {\n", readerName, iterName)
+	fmt.Fprintf(buf, "\tsource := csv.NewReader(r)\n")
+	fmt.Fprintf(buf, "\theaders, err := source.Read()\n")
+	fmt.Fprintf(buf, "\tif err != nil {\n\t\treturn nil, err\n\t}\n")
+	fmt.Fprintf(buf, "\twant := []string{")
+	for i, column := range table.Columns {
+		if i > 0 {
+			fmt.Fprintf(buf, ", ")
+		}
+		fmt.Fprintf(buf, "%q", column.Name)
+	}
+	fmt.Fprintf(buf, "}\n")
+	fmt.Fprintf(buf, "\tif len(headers) != len(want) {\n")
+	fmt.Fprintf(
+		buf,
+		"\t\treturn nil, fmt.Errorf(\"%s: wanted %%d columns, got %%d\", len(want), len(headers))\n",
+		table.Name,
+	)
+	fmt.Fprintf(buf, "\t}\n")
+	fmt.Fprintf(buf, "\tfor i, name := range want {\n")
+	fmt.Fprintf(buf, "\t\tif headers[i] != name {\n")
+	fmt.Fprintf(
+		buf,
+		"\t\t\treturn nil, fmt.Errorf(\"%s: expected column %%d to be '%%s', got '%%s'\", i, name, headers[i])\n",
+		table.Name,
+	)
+	fmt.Fprintf(buf, "\t\t}\n")
+	fmt.Fprintf(buf, "\t}\n")
+	fmt.Fprintf(buf, "\treturn &%s{source: source}, nil\n", iterName)
+	fmt.Fprintf(buf, "}\n")
+
+	fmt.Fprintf(
+		buf,
+		"\n// Next advances the iterator, returning the next %s or nil once "+
+			"the rows are exhausted. Call Err afterward to distinguish "+
+			"end-of-input from a read or scan failure.\n",
+		typeName,
+	)
+	fmt.Fprintf(buf, "func (it *%s) Next() *%s {\n", iterName, typeName)
+	fmt.Fprintf(buf, "\trow, err := it.source.Read()\n")
+	fmt.Fprintf(buf, "\tif err != nil {\n")
+	fmt.Fprintf(buf, "\t\tif err != io.EOF {\n\t\t\tit.err = err\n\t\t}\n")
+	fmt.Fprintf(buf, "\t\treturn nil\n")
+	fmt.Fprintf(buf, "\t}\n")
+	fmt.Fprintf(buf, "\tvar v %s\n", typeName)
+	fmt.Fprintf(buf, "\tif err := v.Scan(row); err != nil {\n")
+	fmt.Fprintf(buf, "\t\tit.err = err\n\t\treturn nil\n")
+	fmt.Fprintf(buf, "\t}\n")
+	fmt.Fprintf(buf, "\treturn &v\n")
+	fmt.Fprintf(buf, "}\n")
+
+	fmt.Fprintf(buf, "\n// Err returns the first error encountered while reading, if any.\n")
+	fmt.Fprintf(buf, "func (it *%s) Err() error { return it.err }\n", iterName)
+}
+
+// genForeignKeyAccessor emits a method on typeName named after the
+// referenced table, walking the foreign key by scanning a caller-supplied
+// slice of the referenced type for a matching row. It takes candidates
+// rather than a Repo so the generated file stays standalone: it has no way
+// to know how the caller loaded the referenced table (this package's Repo,
+// a database, or otherwise), only how to compare two already-loaded rows.
+func genForeignKeyAccessor(
+	buf *bytes.Buffer,
+	typeName string,
+	table TableSpec,
+	fkm ForeignKeyMapping,
+	schema []TableSpec,
+) error {
+	foreign, found := findTable(schema, fkm.ForeignTable)
+	if !found {
+		return fmt.Errorf(
+			"gen: table '%s' not found for foreign key on '%s'",
+			fkm.ForeignTable,
+			table.Name,
+		)
+	}
+	foreignTypeName := goName(string(fkm.ForeignTable))
+	receiver := strings.ToLower(typeName[:1])
+
+	localCols := []Column{}
+	for c := &fkm.LocalColumn; c != nil; c = c.Tail {
+		localCols = append(localCols, *c)
+	}
+	foreignCols := []Column{}
+	for c := &fkm.ForeignColumn; c != nil; c = c.Tail {
+		foreignCols = append(foreignCols, *c)
+	}
+
+	fmt.Fprintf(
+		buf,
+		"\n// %s looks up %s's referenced row among candidates (e.g. every row "+
+			"read from the '%s' table), matching on the foreign key columns.\n",
+		foreignTypeName,
+		receiver,
+		fkm.ForeignTable,
+	)
+	fmt.Fprintf(
+		buf,
+		"func (%s %s) %s(candidates []%s) (*%s, error) {\n",
+		receiver,
+		typeName,
+		foreignTypeName,
+		foreignTypeName,
+		foreignTypeName,
+	)
+	fmt.Fprintf(buf, "\tfor i := range candidates {\n")
+	fmt.Fprintf(buf, "\t\tif ")
+	for i := range localCols {
+		if i > 0 {
+			fmt.Fprintf(buf, " && ")
+		}
+		localColumn, err := findColumn(table, localCols[i].Name)
+		if err != nil {
+			return err
+		}
+		foreignColumn, err := findColumn(foreign, foreignCols[i].Name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(
+			buf,
+			"fmt.Sprint(candidates[i].%s) == fmt.Sprint(%s.%s)",
+			goName(string(foreignColumn.Name)),
+			receiver,
+			goName(string(localColumn.Name)),
+		)
+	}
+	fmt.Fprintf(buf, " {\n")
+	fmt.Fprintf(buf, "\t\t\treturn &candidates[i], nil\n")
+	fmt.Fprintf(buf, "\t\t}\n")
+	fmt.Fprintf(buf, "\t}\n")
+	fmt.Fprintf(
+		buf,
+		"\treturn nil, fmt.Errorf(\"%s: no matching row in '%s'\")\n",
+		table.Name,
+		fkm.ForeignTable,
+	)
+	fmt.Fprintf(buf, "}\n")
+	return nil
+}
+
+func findTable(schema []TableSpec, name TableName) (TableSpec, bool) {
+	for _, table := range schema {
+		if table.Name == name {
+			return table, true
+		}
+	}
+	return TableSpec{}, false
+}
+
+func findColumn(table TableSpec, name ColumnName) (ColumnSpec, error) {
+	for _, column := range table.Columns {
+		if column.Name == name {
+			return column, nil
+		}
+	}
+	return ColumnSpec{}, fmt.Errorf(
+		"gen: column '%s' not found on table '%s'",
+		name,
+		table.Name,
+	)
+}
+
+// goName converts a snake_case schema identifier into an exported
+// PascalCase Go identifier, e.g. "customer_id" -> "CustomerID".
+func goName(name string) string {
+	parts := strings.Split(name, "_")
+	var b strings.Builder
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		if upper := strings.ToUpper(part); commonInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]))
+		b.WriteString(part[1:])
+	}
+	return b.String()
+}
+
+var commonInitialisms = map[string]bool{
+	"ID":   true,
+	"UUID": true,
+	"URL":  true,
+}
+
+type scanExpr struct {
+	// code is either a full expression to assign directly (simple) or an
+	// expression returning (value, error) to be checked (simple == false).
+	code   string
+	simple bool
+}
+
+// goScanExpr renders the expression that converts exprStr (a raw CSV cell)
+// into dt's Go representation.
+func goScanExpr(dt DataType, exprStr string) (scanExpr, error) {
+	switch dt {
+	case DataTypeInt:
+		return scanExpr{code: fmt.Sprintf("strconv.Atoi(%s)", exprStr)}, nil
+	case DataTypeBool:
+		return scanExpr{code: fmt.Sprintf("strconv.ParseBool(%s)", exprStr)}, nil
+	case DataTypeFloat:
+		return scanExpr{
+			code: fmt.Sprintf("strconv.ParseFloat(%s, 64)", exprStr),
+		}, nil
+	case DataTypeString, DataTypeUUID:
+		return scanExpr{code: exprStr, simple: true}, nil
+	}
+
+	typeString := string(dt)
+	switch {
+	case strings.HasPrefix(typeString, "array<") && strings.HasSuffix(typeString, ">"):
+		elemType, sep := arrayElemType(dt)
+		elemGoType, err := goFieldType(elemType)
+		if err != nil {
+			return scanExpr{}, err
+		}
+		return scanExpr{simple: true, code: fmt.Sprintf(
+			"func() []%s {\n"+
+				"\t\tif %s == \"\" {\n"+
+				"\t\t\treturn nil\n"+
+				"\t\t}\n"+
+				"\t\treturn strings.Split(%s, %q)\n"+
+				"\t}()",
+			elemGoType, exprStr, exprStr, sep,
+		)}, nil
+	case strings.HasPrefix(typeString, "decimal(") && strings.HasSuffix(typeString, ")"),
+		strings.HasPrefix(typeString, "enum(") && strings.HasSuffix(typeString, ")"),
+		strings.HasPrefix(typeString, "regex(/") && strings.HasSuffix(typeString, "/)"):
+		// These types are represented as plain strings; the data's
+		// well-formedness is already guaranteed by Validate.
+		return scanExpr{code: exprStr, simple: true}, nil
+	case strings.HasPrefix(typeString, "date(") && strings.HasSuffix(typeString, ")"):
+		return scanExpr{
+			code: fmt.Sprintf("time.Parse(%q, %s)", dateLayout(dt), exprStr),
+		}, nil
+	default:
+		return scanExpr{}, fmt.Errorf("gen: unsupported data type: '%s'", dt)
+	}
+}
+
+// goRowExpr renders the expression that converts field (a Go value of dt's
+// type) back into a CSV cell.
+func goRowExpr(dt DataType, field string) string {
+	switch dt {
+	case DataTypeInt:
+		return fmt.Sprintf("strconv.Itoa(%s)", field)
+	case DataTypeBool:
+		return fmt.Sprintf("strconv.FormatBool(%s)", field)
+	case DataTypeFloat:
+		return fmt.Sprintf("strconv.FormatFloat(%s, 'g', -1, 64)", field)
+	}
+
+	typeString := string(dt)
+	if strings.HasPrefix(typeString, "array<") && strings.HasSuffix(typeString, ">") {
+		_, sep := arrayElemType(dt)
+		return fmt.Sprintf("strings.Join(%s, %q)", field, sep)
+	}
+	if strings.HasPrefix(typeString, "date(") && strings.HasSuffix(typeString, ")") {
+		return fmt.Sprintf("%s.Format(%q)", field, dateLayout(dt))
+	}
+	return field
+}
+
+// goFieldType maps a schema DataType to the Go type a generated struct
+// field uses. Parameterized types without a natural Go equivalent
+// (decimal, enum, regex) are represented as string; array<T> becomes []T;
+// date(fmt) becomes time.Time.
+func goFieldType(dt DataType) (string, error) {
+	switch dt {
+	case DataTypeInt:
+		return "int", nil
+	case DataTypeBool:
+		return "bool", nil
+	case DataTypeFloat:
+		return "float64", nil
+	case DataTypeString, DataTypeUUID:
+		return "string", nil
+	}
+
+	typeString := string(dt)
+	switch {
+	case strings.HasPrefix(typeString, "array<") && strings.HasSuffix(typeString, ">"):
+		elemType, _ := arrayElemType(dt)
+		elemGoType, err := goFieldType(elemType)
+		if err != nil {
+			return "", err
+		}
+		return "[]" + elemGoType, nil
+	case strings.HasPrefix(typeString, "decimal(") && strings.HasSuffix(typeString, ")"),
+		strings.HasPrefix(typeString, "enum(") && strings.HasSuffix(typeString, ")"),
+		strings.HasPrefix(typeString, "regex(/") && strings.HasSuffix(typeString, "/)"):
+		return "string", nil
+	case strings.HasPrefix(typeString, "date(") && strings.HasSuffix(typeString, ")"):
+		return "time.Time", nil
+	default:
+		return "", fmt.Errorf("gen: unsupported data type: '%s'", dt)
+	}
+}
+
+// dateLayout extracts the Go reference-time layout string from a "date(fmt)"
+// type.
+func dateLayout(dt DataType) string {
+	typeString := string(dt)
+	return typeString[len("date(") : len(typeString)-len(")")]
+}
+
+// usesArrayType reports whether any column in schema is an "array<T>" type,
+// so GenerateGo can import "strings" (used by Scan/Row to split/join array
+// cells) only when the generated file actually has one.
+func usesArrayType(schema []TableSpec) bool {
+	for _, table := range schema {
+		for _, column := range table.Columns {
+			typeString := string(column.Type)
+			if strings.HasPrefix(typeString, "array<") && strings.HasSuffix(typeString, ">") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// usesStrconvType reports whether any column in schema is one of the types
+// Scan/Row convert via "strconv" (int, bool, float), so GenerateGo can
+// import it only when the generated file actually uses it.
+func usesStrconvType(schema []TableSpec) bool {
+	for _, table := range schema {
+		for _, column := range table.Columns {
+			switch column.Type {
+			case DataTypeInt, DataTypeBool, DataTypeFloat:
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// usesTimeType reports whether any column in schema (including array
+// elements) needs the "time" package, so GenerateGo can import it only when
+// the generated file actually uses time.Time.
+func usesTimeType(schema []TableSpec) bool {
+	for _, table := range schema {
+		for _, column := range table.Columns {
+			if dataTypeUsesTime(column.Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func dataTypeUsesTime(dt DataType) bool {
+	typeString := string(dt)
+	if strings.HasPrefix(typeString, "date(") && strings.HasSuffix(typeString, ")") {
+		return true
+	}
+	if strings.HasPrefix(typeString, "array<") && strings.HasSuffix(typeString, ">") {
+		elemType, _ := arrayElemType(dt)
+		return dataTypeUsesTime(elemType)
+	}
+	return false
+}
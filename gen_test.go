@@ -0,0 +1,120 @@
+package main
+
+import "testing"
+
+func TestGenerateGoOmitsUnusedImports(t *testing.T) {
+	schema := []TableSpec{{
+		Name: "customers",
+		Columns: []ColumnSpec{
+			{Name: "id", Type: DataTypeString},
+			{Name: "name", Type: DataTypeString},
+		},
+	}}
+
+	out, err := GenerateGo("customers", schema)
+	if err != nil {
+		t.Fatalf("GenerateGo: %v", err)
+	}
+
+	for _, unwanted := range []string{`"strconv"`, `"strings"`, `"time"`} {
+		if countOccurrences(out, unwanted) != 0 {
+			t.Errorf("GenerateGo output imports %s though no column needs it:\n%s", unwanted, out)
+		}
+	}
+	for _, wanted := range []string{`"encoding/csv"`, `"fmt"`, `"io"`} {
+		if countOccurrences(out, wanted) == 0 {
+			t.Errorf("GenerateGo output is missing expected import %s", wanted)
+		}
+	}
+}
+
+func TestGenerateGoIncludesConditionalImports(t *testing.T) {
+	schema := []TableSpec{{
+		Name: "events",
+		Columns: []ColumnSpec{
+			{Name: "id", Type: DataTypeInt},
+			{Name: "tags", Type: DataType("array<string>")},
+			{Name: "happened_on", Type: DataType("date(2006-01-02)")},
+		},
+	}}
+
+	out, err := GenerateGo("events", schema)
+	if err != nil {
+		t.Fatalf("GenerateGo: %v", err)
+	}
+
+	for _, wanted := range []string{`"strconv"`, `"strings"`, `"time"`} {
+		if countOccurrences(out, wanted) == 0 {
+			t.Errorf("GenerateGo output is missing expected import %s for a schema that needs it:\n%s", wanted, out)
+		}
+	}
+}
+
+func TestGenerateGoForeignKeyAccessorTakesCandidateSlice(t *testing.T) {
+	schema := []TableSpec{
+		{
+			Name:       "customers",
+			PrimaryKey: &Column{Name: "id"},
+			Columns: []ColumnSpec{
+				{Name: "id", Type: DataTypeInt, NotNull: true},
+			},
+		},
+		{
+			Name: "orders",
+			Columns: []ColumnSpec{
+				{Name: "id", Type: DataTypeInt, NotNull: true},
+				{Name: "customer_id", Type: DataTypeInt, NotNull: true},
+			},
+			ForeignKeys: []ForeignKeyMapping{{
+				LocalColumn:   Column{Name: "customer_id"},
+				ForeignTable:  "customers",
+				ForeignColumn: Column{Name: "id"},
+			}},
+		},
+	}
+
+	out, err := GenerateGo("orders", schema)
+	if err != nil {
+		t.Fatalf("GenerateGo: %v", err)
+	}
+
+	// The accessor must take a slice of the referenced generated type, not
+	// this package's Repo, so the generated file has no dependency on it.
+	want := "func (o Orders) Customers(candidates []Customers) (*Customers, error) {"
+	if countOccurrences(out, want) == 0 {
+		t.Fatalf("GenerateGo output does not contain expected accessor signature %q:\n%s", want, out)
+	}
+	for _, forbidden := range []string{"Repo)", " Rows"} {
+		if countOccurrences(out, forbidden) != 0 {
+			t.Errorf("GenerateGo output references this package's %q though it must stay standalone:\n%s", forbidden, out)
+		}
+	}
+}
+
+func TestGenerateGoUnknownForeignTableErrors(t *testing.T) {
+	schema := []TableSpec{{
+		Name: "orders",
+		ForeignKeys: []ForeignKeyMapping{{
+			LocalColumn:   Column{Name: "customer_id"},
+			ForeignTable:  "customers",
+			ForeignColumn: Column{Name: "id"},
+		}},
+	}}
+
+	if _, err := GenerateGo("orders", schema); err == nil {
+		t.Fatal("GenerateGo with a foreign key pointing at a missing table returned no error")
+	}
+}
+
+func TestGoNameConvertsSnakeCaseAndInitialisms(t *testing.T) {
+	cases := map[string]string{
+		"customer_id": "CustomerID",
+		"name":        "Name",
+		"order":       "Order",
+	}
+	for in, want := range cases {
+		if got := goName(in); got != want {
+			t.Errorf("goName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import "fmt"
+
+// foreignKeyEdge pairs a ForeignKeyMapping with the table that declared it,
+// so the reverse ("has-many") index can report which table owns each
+// mapping.
+type foreignKeyEdge struct {
+	Table   TableName
+	Mapping ForeignKeyMapping
+}
+
+// SchemaGraph is the foreign key relationship graph across every TableSpec
+// in a schema, indexed in both directions: which tables a given table
+// references (belongs-to) and which tables reference it (has-many).
+type SchemaGraph struct {
+	references   map[TableName][]ForeignKeyMapping
+	referencedBy map[TableName][]foreignKeyEdge
+}
+
+// BuildSchemaGraph walks every ForeignKeyMapping in schema and builds the
+// bidirectional relationship graph between tables.
+func BuildSchemaGraph(schema []TableSpec) *SchemaGraph {
+	g := &SchemaGraph{
+		references:   map[TableName][]ForeignKeyMapping{},
+		referencedBy: map[TableName][]foreignKeyEdge{},
+	}
+	for _, table := range schema {
+		for _, fkm := range table.ForeignKeys {
+			g.references[table.Name] = append(g.references[table.Name], fkm)
+			g.referencedBy[fkm.ForeignTable] = append(
+				g.referencedBy[fkm.ForeignTable],
+				foreignKeyEdge{Table: table.Name, Mapping: fkm},
+			)
+		}
+	}
+	return g
+}
+
+// References returns the foreign key mappings declared by table (its
+// belongs-to relationships).
+func (g *SchemaGraph) References(table TableName) []ForeignKeyMapping {
+	return g.references[table]
+}
+
+// Referenced returns the foreign key mappings of tables that reference
+// table (its has-many relationships).
+func (g *SchemaGraph) Referenced(table TableName) []ForeignKeyMapping {
+	edges := g.referencedBy[table]
+	mappings := make([]ForeignKeyMapping, len(edges))
+	for i, edge := range edges {
+		mappings[i] = edge.Mapping
+	}
+	return mappings
+}
+
+// TopologicalOrder returns schema's tables ordered so that every table
+// referenced by a foreign key appears before the table that references it,
+// allowing parent tables to be loaded first. Foreign keys to tables that are
+// not in schema are ignored. It returns an error describing the offending
+// table if the foreign key graph contains a cycle or a self-reference.
+func (g *SchemaGraph) TopologicalOrder(schema []TableSpec) ([]TableName, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	inSchema := make(map[TableName]bool, len(schema))
+	for _, table := range schema {
+		inSchema[table.Name] = true
+	}
+
+	state := map[TableName]int{}
+	order := make([]TableName, 0, len(schema))
+
+	var visit func(TableName) error
+	visit = func(table TableName) error {
+		switch state[table] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf(
+				"Cycle detected in foreign key graph at table '%s'",
+				table,
+			)
+		}
+		state[table] = visiting
+		for _, fkm := range g.references[table] {
+			if fkm.ForeignTable == table {
+				return fmt.Errorf(
+					"Self-referencing foreign key on table '%s'",
+					table,
+				)
+			}
+			if !inSchema[fkm.ForeignTable] {
+				continue
+			}
+			if err := visit(fkm.ForeignTable); err != nil {
+				return err
+			}
+		}
+		state[table] = visited
+		order = append(order, table)
+		return nil
+	}
+
+	for _, table := range schema {
+		if err := visit(table.Name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// Levels groups schema's tables into topological levels: level 0 has no
+// foreign keys to other tables in the schema, level 1 depends only on
+// tables in level 0, and so on. Tables within a level have no dependency on
+// one another and can safely be validated concurrently. It returns the same
+// error as TopologicalOrder if the graph has a cycle or self-reference.
+func (g *SchemaGraph) Levels(schema []TableSpec) ([][]TableName, error) {
+	order, err := g.TopologicalOrder(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	inSchema := make(map[TableName]bool, len(order))
+	for _, table := range order {
+		inSchema[table] = true
+	}
+
+	depth := make(map[TableName]int, len(order))
+	maxDepth := 0
+	for _, table := range order {
+		for _, fkm := range g.references[table] {
+			if !inSchema[fkm.ForeignTable] {
+				continue
+			}
+			if d := depth[fkm.ForeignTable] + 1; d > depth[table] {
+				depth[table] = d
+			}
+		}
+		if depth[table] > maxDepth {
+			maxDepth = depth[table]
+		}
+	}
+
+	levels := make([][]TableName, maxDepth+1)
+	for _, table := range order {
+		levels[depth[table]] = append(levels[depth[table]], table)
+	}
+	return levels, nil
+}
+
+// columnIndices resolves each name in column's chain to its position in
+// headers, preserving the chain's order.
+func columnIndices(column Column, headers []string) ([]int, error) {
+	indices := make([]int, 0, column.Len())
+OUTER:
+	for c := &column; c != nil; c = c.Tail {
+		for i, header := range headers {
+			if header == string(c.Name) {
+				indices = append(indices, i)
+				continue OUTER
+			}
+		}
+		return nil, fmt.Errorf("Column '%s' not found in headers", c.Name)
+	}
+	return indices, nil
+}
+
+// joinKey builds the composite hash-join key for row over the given column
+// indices.
+func joinKey(row []string, indices []int) string {
+	key := make([]byte, 0, 64)
+	for _, i := range indices {
+		key = append(key, row[i]...)
+		key = append(key, 0)
+	}
+	return string(key)
+}
+
+// hashJoin performs an in-memory hash join of local and foreign keyed on
+// fkm's columns: it first indexes every row of foreign by its foreign key
+// column values, then streams local's rows, invoking f with each local row
+// and its matched foreign row. Local rows with no match are skipped. Every
+// Repo implementation's WithJoin method delegates to this.
+func hashJoin(
+	repo Repo,
+	local, foreign TableName,
+	fkm ForeignKeyMapping,
+	f func(localRow, foreignRow []string) error,
+) error {
+	index := map[string][]string{}
+	if err := repo.WithTable(string(foreign), func(rows Rows) error {
+		foreignCols, err := columnIndices(fkm.ForeignColumn, rows.Headers())
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			row := append([]string{}, rows.Row()...)
+			index[joinKey(row, foreignCols)] = row
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return repo.WithTable(string(local), func(rows Rows) error {
+		localCols, err := columnIndices(fkm.LocalColumn, rows.Headers())
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			foreignRow, found := index[joinKey(rows.Row(), localCols)]
+			if !found {
+				continue
+			}
+			if err := f(rows.Row(), foreignRow); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
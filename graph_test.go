@@ -0,0 +1,216 @@
+package main
+
+import "testing"
+
+func twoTableSchema() []TableSpec {
+	return []TableSpec{
+		{
+			Name:       "customers",
+			PrimaryKey: &Column{Name: "id"},
+			Columns: []ColumnSpec{
+				{Name: "id", Type: DataTypeInt, NotNull: true},
+				{Name: "name", Type: DataTypeString, NotNull: true},
+			},
+		},
+		{
+			Name: "orders",
+			Columns: []ColumnSpec{
+				{Name: "id", Type: DataTypeInt, NotNull: true},
+				{Name: "customer_id", Type: DataTypeInt, NotNull: true},
+			},
+			ForeignKeys: []ForeignKeyMapping{
+				{
+					LocalColumn:   Column{Name: "customer_id"},
+					ForeignTable:  "customers",
+					ForeignColumn: Column{Name: "id"},
+				},
+			},
+		},
+	}
+}
+
+func TestBuildSchemaGraphReferencesAndReferencedBy(t *testing.T) {
+	g := BuildSchemaGraph(twoTableSchema())
+
+	refs := g.References("orders")
+	if len(refs) != 1 || refs[0].ForeignTable != "customers" {
+		t.Fatalf("References(\"orders\") = %+v, want one mapping to 'customers'", refs)
+	}
+	if refs := g.References("customers"); len(refs) != 0 {
+		t.Fatalf("References(\"customers\") = %+v, want none", refs)
+	}
+
+	referencedBy := g.Referenced("customers")
+	if len(referencedBy) != 1 || referencedBy[0].ForeignTable != "customers" {
+		t.Fatalf("Referenced(\"customers\") = %+v, want one mapping from 'orders'", referencedBy)
+	}
+	if referencedBy := g.Referenced("orders"); len(referencedBy) != 0 {
+		t.Fatalf("Referenced(\"orders\") = %+v, want none", referencedBy)
+	}
+}
+
+func TestTopologicalOrderParentsBeforeChildren(t *testing.T) {
+	schema := twoTableSchema()
+	g := BuildSchemaGraph(schema)
+
+	order, err := g.TopologicalOrder(schema)
+	if err != nil {
+		t.Fatalf("TopologicalOrder: %v", err)
+	}
+
+	pos := map[TableName]int{}
+	for i, table := range order {
+		pos[table] = i
+	}
+	if pos["customers"] >= pos["orders"] {
+		t.Fatalf("TopologicalOrder = %v, want 'customers' before 'orders'", order)
+	}
+}
+
+func TestTopologicalOrderDetectsSelfReference(t *testing.T) {
+	schema := []TableSpec{
+		{
+			Name:       "nodes",
+			PrimaryKey: &Column{Name: "id"},
+			Columns: []ColumnSpec{
+				{Name: "id", Type: DataTypeInt, NotNull: true},
+				{Name: "parent_id", Type: DataTypeInt},
+			},
+			ForeignKeys: []ForeignKeyMapping{
+				{
+					LocalColumn:   Column{Name: "parent_id"},
+					ForeignTable:  "nodes",
+					ForeignColumn: Column{Name: "id"},
+				},
+			},
+		},
+	}
+	g := BuildSchemaGraph(schema)
+	if _, err := g.TopologicalOrder(schema); err == nil {
+		t.Fatal("TopologicalOrder on a self-referencing table returned no error")
+	}
+}
+
+func TestTopologicalOrderDetectsCycle(t *testing.T) {
+	schema := []TableSpec{
+		{
+			Name: "a",
+			Columns: []ColumnSpec{
+				{Name: "id", Type: DataTypeInt, NotNull: true},
+				{Name: "b_id", Type: DataTypeInt},
+			},
+			ForeignKeys: []ForeignKeyMapping{
+				{LocalColumn: Column{Name: "b_id"}, ForeignTable: "b", ForeignColumn: Column{Name: "id"}},
+			},
+		},
+		{
+			Name: "b",
+			Columns: []ColumnSpec{
+				{Name: "id", Type: DataTypeInt, NotNull: true},
+				{Name: "a_id", Type: DataTypeInt},
+			},
+			ForeignKeys: []ForeignKeyMapping{
+				{LocalColumn: Column{Name: "a_id"}, ForeignTable: "a", ForeignColumn: Column{Name: "id"}},
+			},
+		},
+	}
+	g := BuildSchemaGraph(schema)
+	if _, err := g.TopologicalOrder(schema); err == nil {
+		t.Fatal("TopologicalOrder on a mutually-referencing pair of tables returned no error")
+	}
+}
+
+func TestLevelsGroupsParentsBeforeChildren(t *testing.T) {
+	schema := twoTableSchema()
+	g := BuildSchemaGraph(schema)
+
+	levels, err := g.Levels(schema)
+	if err != nil {
+		t.Fatalf("Levels: %v", err)
+	}
+	if len(levels) != 2 {
+		t.Fatalf("Levels returned %d levels, want 2: %v", len(levels), levels)
+	}
+	if len(levels[0]) != 1 || levels[0][0] != "customers" {
+		t.Fatalf("Levels[0] = %v, want [customers]", levels[0])
+	}
+	if len(levels[1]) != 1 || levels[1][0] != "orders" {
+		t.Fatalf("Levels[1] = %v, want [orders]", levels[1])
+	}
+}
+
+func TestColumnIndices(t *testing.T) {
+	headers := []string{"id", "customer_id", "total"}
+
+	indices, err := columnIndices(Column{Name: "customer_id"}, headers)
+	if err != nil {
+		t.Fatalf("columnIndices: %v", err)
+	}
+	if len(indices) != 1 || indices[0] != 1 {
+		t.Fatalf("columnIndices = %v, want [1]", indices)
+	}
+
+	composite := Column{Name: "id", Tail: &Column{Name: "total"}}
+	indices, err = columnIndices(composite, headers)
+	if err != nil {
+		t.Fatalf("columnIndices (composite): %v", err)
+	}
+	if len(indices) != 2 || indices[0] != 0 || indices[1] != 2 {
+		t.Fatalf("columnIndices (composite) = %v, want [0 2]", indices)
+	}
+
+	if _, err := columnIndices(Column{Name: "missing"}, headers); err == nil {
+		t.Fatal("columnIndices with a missing column returned no error")
+	}
+}
+
+func TestJoinKeyDistinguishesBoundaries(t *testing.T) {
+	// "ab","c" and "a","bc" must not collide just because their
+	// concatenation is the same string.
+	row1 := []string{"ab", "c"}
+	row2 := []string{"a", "bc"}
+	if joinKey(row1, []int{0, 1}) == joinKey(row2, []int{0, 1}) {
+		t.Fatal("joinKey collided across a column boundary")
+	}
+}
+
+func TestHashJoinMatchesRelatedRows(t *testing.T) {
+	repo := InMemoryRepo{Tables: map[string][][]string{
+		"customers": {
+			{"id", "name"},
+			{"1", "Alice"},
+			{"2", "Bob"},
+		},
+		"orders": {
+			{"id", "customer_id"},
+			{"10", "1"},
+			{"11", "2"},
+			{"12", "999"}, // no matching customer
+		},
+	}}
+
+	fkm := ForeignKeyMapping{
+		LocalColumn:   Column{Name: "customer_id"},
+		ForeignTable:  "customers",
+		ForeignColumn: Column{Name: "id"},
+	}
+
+	matches := map[string]string{}
+	err := hashJoin(repo, "orders", "customers", fkm, func(localRow, foreignRow []string) error {
+		matches[localRow[0]] = foreignRow[1]
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("hashJoin: %v", err)
+	}
+
+	want := map[string]string{"10": "Alice", "11": "Bob"}
+	if len(matches) != len(want) {
+		t.Fatalf("hashJoin matched %v, want %v", matches, want)
+	}
+	for order, name := range want {
+		if matches[order] != name {
+			t.Errorf("hashJoin matched order %s to %q, want %q", order, matches[order], name)
+		}
+	}
+}
@@ -2,22 +2,168 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
+	"fmt"
 	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
 )
 
 func main() {
-	data, err := ioutil.ReadFile("./schema.json")
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "diff":
+			runDiff(os.Args[2:])
+			return
+		case "migrate":
+			runMigrate(os.Args[2:])
+			return
+		case "gen":
+			runGen(os.Args[2:])
+			return
+		}
+	}
+	runValidate()
+}
+
+// runValidate implements `csv-schema [validate] [--json] [--max-violations N]`,
+// validating the CSVs in the current directory against ./schema.csv (if
+// present) or ./schema.json, and printing the resulting ValidationReport as
+// text (the default) or JSON, suitable for CI pipelines and editor
+// (LSP-style) integrations.
+func runValidate() {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "emit the validation report as JSON")
+	maxViolations := fs.Int(
+		"max-violations",
+		0,
+		"stop recording violations after this many (0 means unlimited)",
+	)
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "validate" {
+		args = args[1:]
+	}
+	fs.Parse(args)
+
+	schema, err := loadSchema(".")
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	report, err := Report(
+		FileSystemRepo{"."},
+		schema,
+		noopReporter{},
+		*maxViolations,
+	)
+	if err != nil {
+		log.Fatal("Validation error: ", err)
+	}
+
+	if *asJSON {
+		if err := report.WriteJSON(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+	} else if err := report.WriteText(os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+
+	if len(report.Violations) > 0 {
+		os.Exit(1)
+	}
+}
+
+// loadSchema loads the schema describing directory's CSVs: schema.csv (via
+// ParseSchema) if present, otherwise schema.json.
+func loadSchema(directory string) ([]TableSpec, error) {
+	if _, err := os.Stat(filepath.Join(directory, "schema.csv")); err == nil {
+		return ParseSchema(directory)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(directory, "schema.json"))
+	if err != nil {
+		return nil, err
+	}
 	var schema []TableSpec
 	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+func loadTableSpecs(path string) []TableSpec {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
 		log.Fatal(err)
 	}
 
-	if err := Validate(FileSystemRepo{"."}, schema); err != nil {
-		log.Fatal("Validation error:", err)
+	var schema []TableSpec
+	if err := json.Unmarshal(data, &schema); err != nil {
+		log.Fatal(err)
+	}
+	return schema
+}
+
+// runDiff implements `csv-schema diff old.json new.json`, printing the
+// migration operations required to carry old's schema to new's.
+func runDiff(args []string) {
+	if len(args) != 2 {
+		log.Fatal("usage: csv-schema diff <old.json> <new.json>")
+	}
+
+	ops, err := ComputeDiff(loadTableSpecs(args[0]), loadTableSpecs(args[1]))
+	if err != nil {
+		log.Fatal("Diff error: ", err)
+	}
+
+	for _, op := range ops {
+		fmt.Println(op.String())
+	}
+}
+
+// runMigrate implements
+// `csv-schema migrate --from old.json --to new.json --dialect postgres`,
+// printing the SQL DDL that carries the "from" schema to the "to" schema.
+func runMigrate(args []string) {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	from := fs.String("from", "", "path to the old schema JSON")
+	to := fs.String("to", "", "path to the new schema JSON")
+	dialect := fs.String("dialect", "postgres", "SQL dialect: postgres or sqlite")
+	fs.Parse(args)
+
+	if *from == "" || *to == "" {
+		log.Fatal(
+			"usage: csv-schema migrate --from <old.json> --to <new.json> " +
+				"--dialect <postgres|sqlite>",
+		)
+	}
+
+	ops, err := ComputeDiff(loadTableSpecs(*from), loadTableSpecs(*to))
+	if err != nil {
+		log.Fatal("Diff error: ", err)
+	}
+
+	ddl, err := RenderSQL(*dialect, ops)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print(ddl)
+}
+
+// runGen implements `csv-schema gen --schema schema.json --package mypkg`,
+// printing a standalone Go source file with one struct, Scan/Row methods,
+// and a Read<Table> iterator per table, plus a foreign key accessor method
+// per ForeignKeyMapping.
+func runGen(args []string) {
+	fs := flag.NewFlagSet("gen", flag.ExitOnError)
+	schemaPath := fs.String("schema", "./schema.json", "path to the schema JSON")
+	pkg := fs.String("package", "main", "package name for the generated file")
+	fs.Parse(args)
+
+	src, err := GenerateGo(*pkg, loadTableSpecs(*schemaPath))
+	if err != nil {
+		log.Fatal("Generation error: ", err)
 	}
+	fmt.Print(src)
 }
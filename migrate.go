@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// sqlColumnType maps a schema DataType to its SQL column type for dialect.
+// Unrecognized types fall back to TEXT rather than failing the render,
+// since the richer type system is still growing.
+func sqlColumnType(dialect string, dt DataType) string {
+	switch dt {
+	case DataTypeInt:
+		return "INTEGER"
+	case DataTypeBool:
+		if dialect == "sqlite" {
+			return "INTEGER"
+		}
+		return "BOOLEAN"
+	case DataTypeString:
+		return "TEXT"
+	case DataTypeFloat:
+		if dialect == "sqlite" {
+			return "REAL"
+		}
+		return "DOUBLE PRECISION"
+	case DataTypeUUID:
+		if dialect == "sqlite" {
+			return "TEXT"
+		}
+		return "UUID"
+	}
+
+	typeString := string(dt)
+	switch {
+	case strings.HasPrefix(typeString, "decimal(") && strings.HasSuffix(typeString, ")"):
+		match := decimalTypePattern.FindStringSubmatch(typeString)
+		if match == nil {
+			return "TEXT"
+		}
+		return fmt.Sprintf("NUMERIC(%s,%s)", match[1], match[2])
+	case strings.HasPrefix(typeString, "date(") && strings.HasSuffix(typeString, ")"):
+		if dialect == "sqlite" {
+			return "TEXT"
+		}
+		return "DATE"
+	case strings.HasPrefix(typeString, "array<") && strings.HasSuffix(typeString, ">"):
+		if dialect == "sqlite" {
+			// SQLite has no array type; the generated column stores the
+			// schema's delimited string representation as-is.
+			return "TEXT"
+		}
+		elemType, _ := arrayElemType(dt)
+		return sqlColumnType(dialect, elemType) + "[]"
+	case strings.HasPrefix(typeString, "enum(") && strings.HasSuffix(typeString, ")"),
+		strings.HasPrefix(typeString, "regex(/") && strings.HasSuffix(typeString, "/)"):
+		// Neither dialect gets a first-class representation here: enum
+		// would need a named type (and ALTER TYPE migrations) and regex
+		// has no SQL equivalent at all, so both are stored as their raw
+		// string value instead.
+		return "TEXT"
+	default:
+		return "TEXT"
+	}
+}
+
+// RenderSQL renders ops as SQL DDL statements for dialect ("postgres" or
+// "sqlite"), one statement per op in the order ops lists them. ComputeDiff
+// emits ops in the order required for the statements to apply cleanly:
+// dropped tables, added tables, then per-column/per-constraint changes.
+func RenderSQL(dialect string, ops []MigrationOp) (string, error) {
+	switch dialect {
+	case "postgres", "sqlite":
+	default:
+		return "", fmt.Errorf("Unsupported SQL dialect: '%s'", dialect)
+	}
+
+	buf := &bytes.Buffer{}
+	for _, op := range ops {
+		stmt, err := renderSQLOp(dialect, op)
+		if err != nil {
+			return "", err
+		}
+		if stmt == "" {
+			continue
+		}
+		fmt.Fprintln(buf, stmt)
+	}
+	return buf.String(), nil
+}
+
+func renderSQLOp(dialect string, op MigrationOp) (string, error) {
+	switch op.Kind {
+	case OpAddTable:
+		return renderCreateTable(dialect, op.Table), nil
+	case OpDropTable:
+		return fmt.Sprintf("DROP TABLE %s;", quoteSQLIdentifier(string(op.Table.Name))), nil
+	case OpAddColumn:
+		stmt := fmt.Sprintf(
+			"ALTER TABLE %s ADD COLUMN %s %s",
+			quoteSQLIdentifier(string(op.TableName)),
+			quoteSQLIdentifier(string(op.Column.Name)),
+			sqlColumnType(dialect, op.Column.Type),
+		)
+		if op.Column.NotNull {
+			stmt += " NOT NULL"
+		}
+		return stmt + ";", nil
+	case OpDropColumn:
+		return fmt.Sprintf(
+			"ALTER TABLE %s DROP COLUMN %s;",
+			quoteSQLIdentifier(string(op.TableName)),
+			quoteSQLIdentifier(string(op.Column.Name)),
+		), nil
+	case OpChangeColumnType:
+		if dialect == "sqlite" {
+			// SQLite has no ALTER COLUMN TYPE; callers must rebuild the
+			// table. Surface that explicitly rather than emit bad SQL.
+			return "", fmt.Errorf(
+				"sqlite does not support changing a column's type "+
+					"in-place ('%s'.'%s'); rebuild the table instead",
+				op.TableName,
+				op.Column.Name,
+			)
+		}
+		return fmt.Sprintf(
+			"ALTER TABLE %s ALTER COLUMN %s TYPE %s;",
+			quoteSQLIdentifier(string(op.TableName)),
+			quoteSQLIdentifier(string(op.Column.Name)),
+			sqlColumnType(dialect, op.NewType),
+		), nil
+	case OpAddForeignKey:
+		return fmt.Sprintf(
+			"ALTER TABLE %s ADD FOREIGN KEY (%s) REFERENCES %s (%s);",
+			quoteSQLIdentifier(string(op.TableName)),
+			sqlColumnNames(op.ForeignKey.LocalColumn),
+			quoteSQLIdentifier(string(op.ForeignKey.ForeignTable)),
+			sqlColumnNames(op.ForeignKey.ForeignColumn),
+		), nil
+	case OpDropForeignKey:
+		return fmt.Sprintf(
+			"-- drop foreign key %s.%s -> %s.%s (name your constraint to "+
+				"drop it; the schema does not track constraint names)",
+			op.TableName,
+			sqlColumnNames(op.ForeignKey.LocalColumn),
+			op.ForeignKey.ForeignTable,
+			sqlColumnNames(op.ForeignKey.ForeignColumn),
+		), nil
+	case OpAddUniqueConstraint:
+		return fmt.Sprintf(
+			"ALTER TABLE %s ADD UNIQUE (%s);",
+			quoteSQLIdentifier(string(op.TableName)),
+			sqlColumnNames(op.UniqueColumn),
+		), nil
+	default:
+		return "", fmt.Errorf("Unknown migration op: %s", op.Kind)
+	}
+}
+
+func renderCreateTable(dialect string, table TableSpec) string {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "CREATE TABLE %s (\n", quoteSQLIdentifier(string(table.Name)))
+	for i, column := range table.Columns {
+		fmt.Fprintf(
+			buf,
+			"  %s %s",
+			quoteSQLIdentifier(string(column.Name)),
+			sqlColumnType(dialect, column.Type),
+		)
+		if column.NotNull {
+			buf.WriteString(" NOT NULL")
+		}
+		if i < len(table.Columns)-1 || table.PrimaryKey != nil {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n")
+	}
+	if table.PrimaryKey != nil {
+		fmt.Fprintf(buf, "  PRIMARY KEY (%s)\n", sqlColumnNames(*table.PrimaryKey))
+	}
+	buf.WriteString(");")
+	return buf.String()
+}
+
+// sqlColumnNames renders column's chain as a comma-separated list of quoted
+// identifiers, suitable for a column-list clause in DDL.
+func sqlColumnNames(column Column) string {
+	buf := &bytes.Buffer{}
+	for c := &column; c != nil; c = c.Tail {
+		if c != &column {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(quoteSQLIdentifier(string(c.Name)))
+	}
+	return buf.String()
+}
@@ -0,0 +1,103 @@
+package main
+
+import "testing"
+
+func TestSqlColumnTypeMappings(t *testing.T) {
+	cases := []struct {
+		dialect string
+		dt      DataType
+		want    string
+	}{
+		{"postgres", DataTypeInt, "INTEGER"},
+		{"postgres", DataTypeBool, "BOOLEAN"},
+		{"sqlite", DataTypeBool, "INTEGER"},
+		{"postgres", DataTypeFloat, "DOUBLE PRECISION"},
+		{"sqlite", DataTypeFloat, "REAL"},
+		{"postgres", DataTypeUUID, "UUID"},
+		{"sqlite", DataTypeUUID, "TEXT"},
+		{"postgres", DataType("decimal(10,2)"), "NUMERIC(10,2)"},
+		{"postgres", DataType("date(2006-01-02)"), "DATE"},
+		{"sqlite", DataType("date(2006-01-02)"), "TEXT"},
+		{"postgres", DataType("array<int>"), "INTEGER[]"},
+		{"sqlite", DataType("array<int>"), "TEXT"},
+		{"postgres", DataType("enum(a,b)"), "TEXT"},
+		{"postgres", DataType("regex(/^[a-z]+$/)"), "TEXT"},
+		{"postgres", DataType("garbage"), "TEXT"},
+	}
+	for _, c := range cases {
+		if got := sqlColumnType(c.dialect, c.dt); got != c.want {
+			t.Errorf("sqlColumnType(%q, %q) = %q, want %q", c.dialect, c.dt, got, c.want)
+		}
+	}
+}
+
+func TestRenderSQLRejectsUnknownDialect(t *testing.T) {
+	if _, err := RenderSQL("mysql", nil); err == nil {
+		t.Fatal("RenderSQL with an unsupported dialect returned no error")
+	}
+}
+
+func TestRenderCreateTableQuotesMaliciousIdentifiers(t *testing.T) {
+	table := TableSpec{
+		Name:       TableName(`evil"; DROP TABLE users; --`),
+		PrimaryKey: &Column{Name: `id"); DROP TABLE orders; --`},
+		Columns: []ColumnSpec{
+			{Name: `id"); DROP TABLE orders; --`, Type: DataTypeInt, NotNull: true},
+		},
+	}
+
+	stmt, err := RenderSQL("postgres", []MigrationOp{{Kind: OpAddTable, Table: table}})
+	if err != nil {
+		t.Fatalf("RenderSQL: %v", err)
+	}
+
+	if want := `"evil""; DROP TABLE users; --"`; !contains(stmt, want) {
+		t.Fatalf("rendered SQL %q does not contain quoted table name %q", stmt, want)
+	}
+	if want := `"id""); DROP TABLE orders; --"`; !contains(stmt, want) {
+		t.Fatalf("rendered SQL %q does not contain quoted column name %q", stmt, want)
+	}
+}
+
+func TestRenderSQLOpQuotesAlterStatements(t *testing.T) {
+	malicious := TableName(`t"; DROP TABLE x; --`)
+	ops := []MigrationOp{
+		{Kind: OpDropTable, Table: TableSpec{Name: malicious}},
+		{Kind: OpAddColumn, TableName: malicious, Column: ColumnSpec{Name: "c", Type: DataTypeInt}},
+		{Kind: OpDropColumn, TableName: malicious, Column: ColumnSpec{Name: "c"}},
+	}
+	stmt, err := RenderSQL("postgres", ops)
+	if err != nil {
+		t.Fatalf("RenderSQL: %v", err)
+	}
+	want := `"t""; DROP TABLE x; --"`
+	if got := countOccurrences(stmt, want); got != len(ops) {
+		t.Fatalf("rendered SQL quoted the table name %d times, want %d:\n%s", got, len(ops), stmt)
+	}
+}
+
+func TestRenderSQLChangeColumnTypeRejectedOnSQLite(t *testing.T) {
+	ops := []MigrationOp{{
+		Kind:      OpChangeColumnType,
+		TableName: "t",
+		Column:    ColumnSpec{Name: "c"},
+		NewType:   DataTypeInt,
+	}}
+	if _, err := RenderSQL("sqlite", ops); err == nil {
+		t.Fatal("RenderSQL changing a column's type on sqlite returned no error")
+	}
+}
+
+func contains(haystack, needle string) bool {
+	return countOccurrences(haystack, needle) > 0
+}
+
+func countOccurrences(haystack, needle string) int {
+	count := 0
+	for i := 0; i+len(needle) <= len(haystack); i++ {
+		if haystack[i:i+len(needle)] == needle {
+			count++
+		}
+	}
+	return count
+}
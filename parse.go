@@ -8,50 +8,22 @@ import (
 	"strings"
 )
 
-var metaschema = []Table{
-	Table{
+// metaschema describes schema.csv itself (one row per table/column pair, in
+// the same column order RenderMetaschemaCSV writes), so ParseSchema can
+// validate a schema.csv file the same way Validate checks any other table.
+var metaschema = []TableSpec{
+	{
 		Name: "schema.csv",
-		Columns: []Column{
-			Column{
-				Name:    "table",
-				NotNull: true,
-				Type:    DataTypeString{},
-			},
-			Column{
-				Name:    "column",
-				NotNull: true,
-				Type:    DataTypeString{},
-			},
-			Column{
-				Name:    "not_null",
-				NotNull: true,
-				Type:    DataTypeBool{},
-			},
-			Column{
-				Name:    "unique",
-				NotNull: true,
-				Type:    DataTypeBool{},
-			},
-			Column{
-				Name:    "primary_key",
-				NotNull: true,
-				Type:    DataTypeBool{},
-			},
-			Column{
-				Name:    "type",
-				NotNull: true,
-				Type:    DataTypeString{},
-			},
-			Column{
-				Name:    "references_table",
-				Type:    DataTypeString{},
-				NotNull: false,
-			},
-			Column{
-				Name:    "references_column",
-				Type:    DataTypeString{},
-				NotNull: false,
-			},
+		Columns: []ColumnSpec{
+			{Name: "table", NotNull: true, Type: DataTypeString},
+			{Name: "column", NotNull: true, Type: DataTypeString},
+			{Name: "not_null", NotNull: true, Type: DataTypeBool},
+			{Name: "unique", NotNull: true, Type: DataTypeBool},
+			{Name: "primary_key", NotNull: true, Type: DataTypeBool},
+			{Name: "type", NotNull: true, Type: DataTypeString},
+			{Name: "null", NotNull: false, Type: DataTypeString},
+			{Name: "references_table", NotNull: false, Type: DataTypeString},
+			{Name: "references_column", NotNull: false, Type: DataTypeString},
 		},
 	},
 }
@@ -59,19 +31,28 @@ var metaschema = []Table{
 func parseColumnType(typeString string) (DataType, error) {
 	switch typeString {
 	case "int":
-		return DataTypeInt{}, nil
+		return DataTypeInt, nil
 	case "bool":
-		return DataTypeBool{}, nil
+		return DataTypeBool, nil
 	case "string":
-		return DataTypeString{}, nil
+		return DataTypeString, nil
+	case "float":
+		return DataTypeFloat, nil
+	case "uuid":
+		return DataTypeUUID, nil
 	default:
-		if strings.HasPrefix(typeString, "date(") &&
-			strings.HasSuffix(typeString, ")") {
-			return DataTypeDate{
-				Format: typeString[len("date(") : len(typeString)-len(")")],
-			}, nil
+		// decimal(p,s), enum(a|b|c), array<T>, regex(/pattern/), and
+		// date(fmt) all carry their parameters in the type string itself;
+		// ValidateDataType parses them on demand, so the loader just passes
+		// them through.
+		for _, prefix := range []string{
+			"decimal(", "enum(", "array<", "regex(/", "date(",
+		} {
+			if strings.HasPrefix(typeString, prefix) {
+				return DataType(typeString), nil
+			}
 		}
-		return nil, fmt.Errorf("Couldn't match type: '%s'", typeString)
+		return "", fmt.Errorf("Couldn't match type: '%s'", typeString)
 	}
 }
 
@@ -86,7 +67,42 @@ func mustParseBool(b string) bool {
 	}
 }
 
-func ParseSchema(directory string) ([]Table, error) {
+// tableBuilder accumulates a TableSpec's columns, primary-key chain, unique
+// columns, and foreign keys across the several schema.csv rows (one per
+// column) that describe it.
+type tableBuilder struct {
+	spec       TableSpec
+	pkeyTail   *Column
+	pkeyColumn []ColumnName
+}
+
+// addPrimaryKey appends name to the table's composite primary key, in the
+// order its rows appear in schema.csv.
+func (b *tableBuilder) addPrimaryKey(name ColumnName) {
+	b.pkeyColumn = append(b.pkeyColumn, name)
+}
+
+// build finalizes the table's primary key chain from the column names
+// collected by addPrimaryKey.
+func (b *tableBuilder) build() TableSpec {
+	if len(b.pkeyColumn) > 0 {
+		var chain func(names []ColumnName) Column
+		chain = func(names []ColumnName) Column {
+			if len(names) == 1 {
+				return Column{Name: names[0]}
+			}
+			tail := chain(names[1:])
+			return Column{Name: names[0], Tail: &tail}
+		}
+		pkey := chain(b.pkeyColumn)
+		b.spec.PrimaryKey = &pkey
+	}
+	return b.spec
+}
+
+// ParseSchema loads and validates a schema.csv metaschema file from
+// directory, returning the []TableSpec it describes.
+func ParseSchema(directory string) ([]TableSpec, error) {
 	if err := Validate(FileSystemRepo{directory}, metaschema); err != nil {
 		return nil, err
 	}
@@ -103,8 +119,8 @@ func ParseSchema(directory string) ([]Table, error) {
 		return nil, err
 	}
 
-	// Because of validation, we know there is an initial column and that all
-	// of the columns in the schema exist; however, we don't know their
+	// Because of validation, we know there is a header row and that all of
+	// the columns in the schema exist; however, we don't know their
 	// positions, so we have to search for them.
 	headers, rows := records[0], records[1:]
 	var columnTable int
@@ -112,9 +128,10 @@ func ParseSchema(directory string) ([]Table, error) {
 	var columnNotNull int
 	var columnUnique int
 	var columnPrimaryKey int
+	var columnType int
+	var columnNull int
 	var columnReferencesTable int
 	var columnReferencesColumn int
-	var columnType int
 	for i, header := range headers {
 		switch header {
 		case "table":
@@ -129,6 +146,8 @@ func ParseSchema(directory string) ([]Table, error) {
 			columnPrimaryKey = i
 		case "type":
 			columnType = i
+		case "null":
+			columnNull = i
 		case "references_table":
 			columnReferencesTable = i
 		case "references_column":
@@ -136,13 +155,18 @@ func ParseSchema(directory string) ([]Table, error) {
 		}
 	}
 
-	tables := map[string]Table{}
+	builders := map[TableName]*tableBuilder{}
+	var order []TableName
 	for i, row := range rows {
-		table, found := tables[row[columnTable]]
+		tableName := TableName(row[columnTable])
+		builder, found := builders[tableName]
 		if !found {
-			table.Name = row[columnTable]
+			builder = &tableBuilder{spec: TableSpec{Name: tableName}}
+			builders[tableName] = builder
+			order = append(order, tableName)
 		}
-		columnType, err := parseColumnType(row[columnType])
+
+		colType, err := parseColumnType(row[columnType])
 		if err != nil {
 			return nil, fmt.Errorf(
 				"Error parsing column type on line %d: %v",
@@ -150,29 +174,36 @@ func ParseSchema(directory string) ([]Table, error) {
 				err,
 			)
 		}
-		var columnRef *ColumnRef
-		if row[columnReferencesTable] != "" &&
-			row[columnReferencesColumn] != "" {
-			columnRef = &ColumnRef{
-				Table:  row[columnReferencesTable],
-				Column: row[columnReferencesColumn],
-			}
-		}
 
-		table.Columns = append(table.Columns, Column{
-			Name:       row[columnColumn],
-			NotNull:    mustParseBool(row[columnNotNull]),
-			Unique:     mustParseBool(row[columnUnique]),
-			PrimaryKey: mustParseBool(row[columnPrimaryKey]),
-			Type:       columnType,
-			References: columnRef,
+		columnName := ColumnName(row[columnColumn])
+		builder.spec.Columns = append(builder.spec.Columns, ColumnSpec{
+			Name:    columnName,
+			Type:    colType,
+			NotNull: mustParseBool(row[columnNotNull]),
+			Null:    row[columnNull],
 		})
-		tables[table.Name] = table
+
+		if mustParseBool(row[columnUnique]) {
+			builder.spec.UniqueColumns = append(
+				builder.spec.UniqueColumns,
+				Column{Name: columnName},
+			)
+		}
+		if mustParseBool(row[columnPrimaryKey]) {
+			builder.addPrimaryKey(columnName)
+		}
+		if row[columnReferencesTable] != "" && row[columnReferencesColumn] != "" {
+			builder.spec.ForeignKeys = append(builder.spec.ForeignKeys, ForeignKeyMapping{
+				LocalColumn:   Column{Name: columnName},
+				ForeignTable:  TableName(row[columnReferencesTable]),
+				ForeignColumn: Column{Name: ColumnName(row[columnReferencesColumn])},
+			})
+		}
 	}
 
-	schema := make([]Table, 0, len(tables))
-	for _, table := range tables {
-		schema = append(schema, table)
+	schema := make([]TableSpec, 0, len(order))
+	for _, name := range order {
+		schema = append(schema, builders[name].build())
 	}
 	return schema, nil
 }
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeSchemaCSV(t *testing.T, dir string, schema []TableSpec) {
+	t.Helper()
+	out, err := RenderMetaschemaCSV(schema)
+	if err != nil {
+		t.Fatalf("RenderMetaschemaCSV: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "schema.csv"), out, 0o644); err != nil {
+		t.Fatalf("writing schema.csv: %v", err)
+	}
+}
+
+func TestParseSchemaRoundTripsRenderedMetaschema(t *testing.T) {
+	dir := t.TempDir()
+	writeSchemaCSV(t, dir, twoTableSchema())
+
+	got, err := ParseSchema(dir)
+	if err != nil {
+		t.Fatalf("ParseSchema: %v", err)
+	}
+
+	byName := map[TableName]TableSpec{}
+	for _, table := range got {
+		byName[table.Name] = table
+	}
+
+	customers, found := byName["customers"]
+	if !found {
+		t.Fatal("ParseSchema did not return a 'customers' table")
+	}
+	if customers.PrimaryKey == nil || customers.PrimaryKey.Name != "id" {
+		t.Fatalf("customers.PrimaryKey = %+v, want Column{Name: \"id\"}", customers.PrimaryKey)
+	}
+	if len(customers.Columns) != 2 {
+		t.Fatalf("customers.Columns = %v, want 2 columns", customers.Columns)
+	}
+
+	orders, found := byName["orders"]
+	if !found {
+		t.Fatal("ParseSchema did not return an 'orders' table")
+	}
+	if len(orders.ForeignKeys) != 1 {
+		t.Fatalf("orders.ForeignKeys = %v, want exactly 1", orders.ForeignKeys)
+	}
+	fkm := orders.ForeignKeys[0]
+	if fkm.LocalColumn.Name != "customer_id" || fkm.ForeignTable != "customers" || fkm.ForeignColumn.Name != "id" {
+		t.Fatalf("orders.ForeignKeys[0] = %+v, want customer_id -> customers.id", fkm)
+	}
+}
+
+func TestParseSchemaParsesParameterizedTypes(t *testing.T) {
+	dir := t.TempDir()
+	schema := []TableSpec{{
+		Name: "items",
+		Columns: []ColumnSpec{
+			{Name: "id", Type: DataTypeInt, NotNull: true},
+			{Name: "price", Type: DataType("decimal(10,2)"), NotNull: true},
+			{Name: "purchased_on", Type: DataType("date(2006-01-02)"), NotNull: true},
+			{Name: "tags", Type: DataType("array<string>"), NotNull: true},
+		},
+	}}
+	writeSchemaCSV(t, dir, schema)
+
+	got, err := ParseSchema(dir)
+	if err != nil {
+		t.Fatalf("ParseSchema: %v", err)
+	}
+	if len(got) != 1 || len(got[0].Columns) != 4 {
+		t.Fatalf("ParseSchema = %+v, want 1 table with 4 columns", got)
+	}
+
+	byName := map[ColumnName]ColumnSpec{}
+	for _, column := range got[0].Columns {
+		byName[column.Name] = column
+	}
+	if byName["price"].Type != DataType("decimal(10,2)") {
+		t.Errorf("price type = %q, want decimal(10,2)", byName["price"].Type)
+	}
+	if byName["purchased_on"].Type != DataType("date(2006-01-02)") {
+		t.Errorf("purchased_on type = %q, want date(2006-01-02)", byName["purchased_on"].Type)
+	}
+	if byName["tags"].Type != DataType("array<string>") {
+		t.Errorf("tags type = %q, want array<string>", byName["tags"].Type)
+	}
+}
+
+func TestParseSchemaRejectsUnknownType(t *testing.T) {
+	dir := t.TempDir()
+	content := "table,column,not_null,unique,primary_key,type,null,references_table,references_column\n" +
+		"widgets,id,true,false,false,bogus,,,\n"
+	if err := os.WriteFile(filepath.Join(dir, "schema.csv"), []byte(content), 0o644); err != nil {
+		t.Fatalf("writing schema.csv: %v", err)
+	}
+
+	if _, err := ParseSchema(dir); err == nil {
+		t.Fatal("ParseSchema with an unrecognized column type returned no error")
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// SQLRepo adapts a SQL database as a Repo, running `SELECT * FROM <table>`
+// against DB and adapting each result column back to the string-based
+// values the schema's validators expect. This lets the same Validate logic
+// check a live database the same way it checks a directory of CSVs.
+type SQLRepo struct {
+	DB *sql.DB
+}
+
+type sqlRows struct {
+	rows    *sql.Rows
+	headers []string
+	current []string
+	err     error
+}
+
+func (r *sqlRows) Headers() []string { return r.headers }
+func (r *sqlRows) Row() []string     { return r.current }
+func (r *sqlRows) Err() error        { return r.err }
+
+func (r *sqlRows) Next() bool {
+	if r.err != nil || !r.rows.Next() {
+		return false
+	}
+
+	values := make([]sql.NullString, len(r.headers))
+	dest := make([]interface{}, len(values))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if r.err = r.rows.Scan(dest...); r.err != nil {
+		return false
+	}
+
+	row := make([]string, len(values))
+	for i, value := range values {
+		if value.Valid {
+			row[i] = value.String
+		}
+	}
+	r.current = row
+	return true
+}
+
+func (sr SQLRepo) WithTable(table string, f func(r Rows) error) error {
+	rows, err := sr.DB.Query(
+		fmt.Sprintf("SELECT * FROM %s", quoteSQLIdentifier(table)),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	if err := f(&sqlRows{rows: rows, headers: columns}); err != nil {
+		return err
+	}
+
+	return rows.Err()
+}
+
+// quoteSQLIdentifier renders table as a double-quoted SQL identifier,
+// doubling any embedded quote character. This closes off SQL injection via
+// the table name: table names reach SQLRepo from TableSpec, which (via diff/migrate/gen)
+// can originate in a JSON or CSV file the caller doesn't fully control, so
+// "table comes from the schema" isn't a safety argument on its own.
+func quoteSQLIdentifier(table string) string {
+	return `"` + strings.ReplaceAll(table, `"`, `""`) + `"`
+}
+
+func (sr SQLRepo) WithJoin(
+	local, foreign TableName,
+	fkm ForeignKeyMapping,
+	f func(localRow, foreignRow []string) error,
+) error {
+	return hashJoin(sr, local, foreign, fkm, f)
+}
+
+// InMemoryRepo is a Repo backed by fixture data, primarily for tests: each
+// table is a slice of rows, the first of which is the header row.
+type InMemoryRepo struct {
+	Tables map[string][][]string
+}
+
+type inMemoryRows struct {
+	headers []string
+	rows    [][]string
+	current []string
+	pos     int
+}
+
+func (r *inMemoryRows) Headers() []string { return r.headers }
+func (r *inMemoryRows) Row() []string     { return r.current }
+func (r *inMemoryRows) Err() error        { return nil }
+
+func (r *inMemoryRows) Next() bool {
+	if r.pos >= len(r.rows) {
+		return false
+	}
+	r.current = r.rows[r.pos]
+	r.pos++
+	return true
+}
+
+func (ir InMemoryRepo) WithTable(table string, f func(r Rows) error) error {
+	data, found := ir.Tables[table]
+	if !found {
+		return fmt.Errorf("Table not found: '%s'", table)
+	}
+	if len(data) < 1 {
+		return fmt.Errorf("Table '%s' has no header row", table)
+	}
+	return f(&inMemoryRows{headers: data[0], rows: data[1:]})
+}
+
+func (ir InMemoryRepo) WithJoin(
+	local, foreign TableName,
+	fkm ForeignKeyMapping,
+	f func(localRow, foreignRow []string) error,
+) error {
+	return hashJoin(ir, local, foreign, fkm, f)
+}
+
+// MultiRepo fans a single table lookup out to several backends, so a caller
+// can, for example, confirm that a CSV export matches the schema of an
+// existing database by validating both at once.
+type MultiRepo struct {
+	Repos []Repo
+}
+
+func (mr MultiRepo) WithTable(table string, f func(r Rows) error) error {
+	for _, repo := range mr.Repos {
+		if err := repo.WithTable(table, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (mr MultiRepo) WithJoin(
+	local, foreign TableName,
+	fkm ForeignKeyMapping,
+	f func(localRow, foreignRow []string) error,
+) error {
+	for _, repo := range mr.Repos {
+		if err := repo.WithJoin(local, foreign, fkm, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import "testing"
+
+func TestQuoteSQLIdentifierEscapesEmbeddedQuotes(t *testing.T) {
+	got := quoteSQLIdentifier(`evil"; DROP TABLE users; --`)
+	want := `"evil""; DROP TABLE users; --"`
+	if got != want {
+		t.Fatalf("quoteSQLIdentifier = %s, want %s", got, want)
+	}
+}
+
+func TestQuoteSQLIdentifierPlainName(t *testing.T) {
+	if got, want := quoteSQLIdentifier("customers"), `"customers"`; got != want {
+		t.Fatalf("quoteSQLIdentifier = %s, want %s", got, want)
+	}
+}
+
+func TestInMemoryRepoWithTableMissingTable(t *testing.T) {
+	repo := InMemoryRepo{Tables: map[string][][]string{}}
+	err := repo.WithTable("nope", func(r Rows) error { return nil })
+	if err == nil {
+		t.Fatal("WithTable on a missing table returned no error")
+	}
+}
+
+func TestInMemoryRepoWithTableIteratesRows(t *testing.T) {
+	repo := InMemoryRepo{Tables: map[string][][]string{
+		"customers": {
+			{"id", "name"},
+			{"1", "Alice"},
+			{"2", "Bob"},
+		},
+	}}
+
+	var got [][]string
+	err := repo.WithTable("customers", func(r Rows) error {
+		if got, want := r.Headers(), []string{"id", "name"}; got[0] != want[0] || got[1] != want[1] {
+			t.Fatalf("Headers() = %v, want %v", got, want)
+		}
+		for r.Next() {
+			got = append(got, append([]string(nil), r.Row()...))
+		}
+		return r.Err()
+	})
+	if err != nil {
+		t.Fatalf("WithTable: %v", err)
+	}
+	if len(got) != 2 || got[0][1] != "Alice" || got[1][1] != "Bob" {
+		t.Fatalf("collected rows = %v, want [[1 Alice] [2 Bob]]", got)
+	}
+}
+
+func TestMultiRepoWithTableFansOutToEveryBackend(t *testing.T) {
+	a := InMemoryRepo{Tables: map[string][][]string{
+		"customers": {{"id"}, {"1"}},
+	}}
+	b := InMemoryRepo{Tables: map[string][][]string{
+		"customers": {{"id"}, {"2"}},
+	}}
+	repo := MultiRepo{Repos: []Repo{a, b}}
+
+	var seen []string
+	err := repo.WithTable("customers", func(r Rows) error {
+		for r.Next() {
+			seen = append(seen, r.Row()[0])
+		}
+		return r.Err()
+	})
+	if err != nil {
+		t.Fatalf("WithTable: %v", err)
+	}
+	if len(seen) != 2 || seen[0] != "1" || seen[1] != "2" {
+		t.Fatalf("seen = %v, want [1 2]", seen)
+	}
+}
+
+func TestMultiRepoWithTablePropagatesError(t *testing.T) {
+	repo := MultiRepo{Repos: []Repo{
+		InMemoryRepo{Tables: map[string][][]string{}},
+	}}
+	if err := repo.WithTable("missing", func(r Rows) error { return nil }); err == nil {
+		t.Fatal("WithTable propagated no error from a failing backend")
+	}
+}
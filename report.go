@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ViolationKind classifies a single problem found while validating a schema
+// or its data.
+type ViolationKind string
+
+const (
+	SchemaError     ViolationKind = "schema_error"
+	TypeError       ViolationKind = "type_error"
+	NullViolation   ViolationKind = "null_violation"
+	PKDuplicate     ViolationKind = "pk_duplicate"
+	FKMissing       ViolationKind = "fk_missing"
+	UniqueViolation ViolationKind = "unique_violation"
+)
+
+// Violation is a single, classified problem found during validation, naming
+// the table, row, and column it was found in where those are applicable.
+type Violation struct {
+	Kind    ViolationKind `json:"kind"`
+	Table   TableName     `json:"table,omitempty"`
+	Row     int           `json:"row,omitempty"`
+	Column  ColumnName    `json:"column,omitempty"`
+	Value   string        `json:"value,omitempty"`
+	Message string        `json:"message"`
+}
+
+func (v Violation) String() string {
+	switch {
+	case v.Row != 0 && v.Column != "":
+		return fmt.Sprintf(
+			"%s: table '%s' row %d column '%s': %s",
+			v.Kind, v.Table, v.Row, v.Column, v.Message,
+		)
+	case v.Column != "":
+		return fmt.Sprintf(
+			"%s: table '%s' column '%s': %s",
+			v.Kind, v.Table, v.Column, v.Message,
+		)
+	case v.Table != "":
+		return fmt.Sprintf("%s: table '%s': %s", v.Kind, v.Table, v.Message)
+	default:
+		return fmt.Sprintf("%s: %s", v.Kind, v.Message)
+	}
+}
+
+// ValidationReport accumulates every violation found while validating a
+// schema against a Repo, up to MaxViolations, rather than stopping at the
+// first one. This lets a caller fix all the problems in a dirty CSV in one
+// pass instead of fix-one-rerun-fix-the-next.
+type ValidationReport struct {
+	// MaxViolations caps how many violations are recorded; 0 means
+	// unlimited. Once the cap is hit, Truncated is set and further
+	// violations are silently dropped.
+	MaxViolations int
+
+	Violations []Violation
+	Truncated  bool
+
+	mu sync.Mutex
+}
+
+// Add records v, returning false if the report was already at
+// MaxViolations (in which case v is dropped and Truncated is set).
+func (r *ValidationReport) Add(v Violation) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.MaxViolations > 0 && len(r.Violations) >= r.MaxViolations {
+		r.Truncated = true
+		return false
+	}
+	r.Violations = append(r.Violations, v)
+	return true
+}
+
+// Full reports whether the report has reached MaxViolations, so callers can
+// stop checking a row or table once nothing more can be recorded.
+func (r *ValidationReport) Full() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.MaxViolations > 0 && len(r.Violations) >= r.MaxViolations
+}
+
+// Error implements the error interface so a *ValidationReport can still be
+// handled like any other error by callers that only care about pass/fail.
+func (r *ValidationReport) Error() string {
+	buf := &bytes.Buffer{}
+	r.WriteText(buf)
+	return buf.String()
+}
+
+// WriteText writes one human-readable line per violation to w.
+func (r *ValidationReport) WriteText(w io.Writer) error {
+	for _, v := range r.Violations {
+		if _, err := fmt.Fprintln(w, v.String()); err != nil {
+			return err
+		}
+	}
+	if r.Truncated {
+		if _, err := fmt.Fprintf(
+			w,
+			"... truncated after %d violations\n",
+			len(r.Violations),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the report as JSON, suitable for CI pipelines and editor
+// (LSP-style) integrations.
+func (r *ValidationReport) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(struct {
+		Violations []Violation `json:"violations"`
+		Truncated  bool        `json:"truncated"`
+	}{r.Violations, r.Truncated})
+}
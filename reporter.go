@@ -0,0 +1,25 @@
+package main
+
+// ValidationReporter receives progress and per-table statistics as Validate
+// runs, so callers validating large CSVs can surface a progress indicator
+// or a summary instead of waiting on a silent, possibly long-running call.
+type ValidationReporter interface {
+	// TableStarted is called once, when a table's validation begins.
+	TableStarted(table TableName)
+
+	// RowValidated is called after each row in table is checked.
+	RowValidated(table TableName, rowNum int)
+
+	// TableFinished is called once a table's validation completes, with
+	// the total number of rows checked and the error it finished with, if
+	// any.
+	TableFinished(table TableName, rows int, err error)
+}
+
+// noopReporter is the ValidationReporter Validate uses when the caller
+// doesn't supply one.
+type noopReporter struct{}
+
+func (noopReporter) TableStarted(TableName)             {}
+func (noopReporter) RowValidated(TableName, int)        {}
+func (noopReporter) TableFinished(TableName, int, error) {}
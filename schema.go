@@ -4,15 +4,34 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// DataType names a column's value type. Beyond the fixed values below, it
+// also carries parameterized types as plain strings, parsed on demand by
+// ValidateDataType: "decimal(p,s)", "enum(a|b|c)", "array<T>" (optionally
+// "array<T;sep>" for a separator other than the default comma),
+// "regex(/pattern/)", and "date(fmt)" (fmt is a Go reference-time layout
+// string, e.g. "date(2006-01-02)").
 type DataType string
 
 const (
 	DataTypeInt    DataType = "int"
 	DataTypeString DataType = "string"
 	DataTypeBool   DataType = "bool"
+	DataTypeFloat  DataType = "float"
+	DataTypeUUID   DataType = "uuid"
+)
+
+var (
+	decimalTypePattern = regexp.MustCompile(`^decimal\((\d+),(\d+)\)$`)
+	uuidPattern        = regexp.MustCompile(
+		`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-` +
+			`[0-9a-fA-F]{12}$`,
+	)
 )
 
 func ValidateDataType(dt DataType, value string) error {
@@ -29,11 +48,157 @@ func ValidateDataType(dt DataType, value string) error {
 		default:
 			return fmt.Errorf("Illegal value for type 'bool': '%s'", value)
 		}
+	case DataTypeFloat:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("Illegal value for type 'float': '%s'", value)
+		}
+		return nil
+	case DataTypeUUID:
+		if !uuidPattern.MatchString(value) {
+			return fmt.Errorf("Illegal value for type 'uuid': '%s'", value)
+		}
+		return nil
 	case DataTypeString:
 		return nil
+	}
+
+	typeString := string(dt)
+	switch {
+	case strings.HasPrefix(typeString, "decimal(") &&
+		strings.HasSuffix(typeString, ")"):
+		return validateDecimal(dt, value)
+	case strings.HasPrefix(typeString, "enum(") &&
+		strings.HasSuffix(typeString, ")"):
+		return validateEnum(dt, value)
+	case strings.HasPrefix(typeString, "array<") &&
+		strings.HasSuffix(typeString, ">"):
+		return validateArray(dt, value)
+	case strings.HasPrefix(typeString, "regex(/") &&
+		strings.HasSuffix(typeString, "/)"):
+		return validateRegexConstrained(dt, value)
+	case strings.HasPrefix(typeString, "date(") &&
+		strings.HasSuffix(typeString, ")"):
+		return validateDate(dt, value)
 	default:
-		panic(fmt.Sprintf("Invalid data type: '%s'", dt))
+		return fmt.Errorf("Invalid data type: '%s'", dt)
+	}
+}
+
+// validateDecimal checks value against a "decimal(precision,scale)" type:
+// value must be a base-10 number with at most scale digits after the point
+// and at most precision digits overall.
+func validateDecimal(dt DataType, value string) error {
+	match := decimalTypePattern.FindStringSubmatch(string(dt))
+	if match == nil {
+		return fmt.Errorf("Invalid decimal type spec: '%s'", dt)
+	}
+	precision, _ := strconv.Atoi(match[1])
+	scale, _ := strconv.Atoi(match[2])
+
+	digits := strings.TrimPrefix(strings.TrimPrefix(value, "-"), "+")
+	intPart, fracPart := digits, ""
+	if i := strings.IndexByte(digits, '.'); i >= 0 {
+		intPart, fracPart = digits[:i], digits[i+1:]
+	}
+	if intPart == "" || !isDigitString(intPart) || !isDigitString(fracPart) {
+		return fmt.Errorf("Illegal value for type '%s': '%s'", dt, value)
+	}
+	if len(fracPart) > scale {
+		return fmt.Errorf(
+			"Value '%s' has more than %d digits of scale for type '%s'",
+			value,
+			scale,
+			dt,
+		)
+	}
+	if len(intPart)+len(fracPart) > precision {
+		return fmt.Errorf(
+			"Value '%s' has more than %d digits of precision for type '%s'",
+			value,
+			precision,
+			dt,
+		)
+	}
+	return nil
+}
+
+func isDigitString(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// validateEnum checks value against an "enum(a|b|c)" type's pipe-delimited
+// membership list.
+func validateEnum(dt DataType, value string) error {
+	members := string(dt)[len("enum(") : len(dt)-len(")")]
+	for _, member := range strings.Split(members, "|") {
+		if member == value {
+			return nil
+		}
+	}
+	return fmt.Errorf("Illegal value for type '%s': '%s'", dt, value)
+}
+
+// validateArray checks value against an "array<T>" (or "array<T;sep>") type
+// by splitting on the separator (default ",") and validating each element
+// against T. An empty value is treated as an empty array.
+func validateArray(dt DataType, value string) error {
+	elemType, sep := arrayElemType(dt)
+	if value == "" {
+		return nil
+	}
+	for _, elem := range strings.Split(value, sep) {
+		if err := ValidateDataType(elemType, elem); err != nil {
+			return fmt.Errorf(
+				"Illegal array element for type '%s': %v",
+				dt,
+				err,
+			)
+		}
 	}
+	return nil
+}
+
+// arrayElemType splits an "array<T>" (or "array<T;sep>") type into its
+// element type and separator (default ",").
+func arrayElemType(dt DataType) (DataType, string) {
+	inner := string(dt)[len("array<") : len(dt)-len(">")]
+	elemType, sep := inner, ","
+	if i := strings.IndexByte(inner, ';'); i >= 0 {
+		elemType, sep = inner[:i], inner[i+1:]
+	}
+	return DataType(elemType), sep
+}
+
+// validateRegexConstrained checks value against a "regex(/pattern/)" type.
+func validateRegexConstrained(dt DataType, value string) error {
+	pattern := string(dt)[len("regex(/") : len(dt)-len("/)")]
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("Invalid regex type spec '%s': %v", dt, err)
+	}
+	if !re.MatchString(value) {
+		return fmt.Errorf(
+			"Value '%s' does not match pattern for type '%s'",
+			value,
+			dt,
+		)
+	}
+	return nil
+}
+
+// validateDate checks value against a "date(fmt)" type, where fmt is a Go
+// reference-time layout string (e.g. "2006-01-02").
+func validateDate(dt DataType, value string) error {
+	layout := string(dt)[len("date(") : len(dt)-len(")")]
+	if _, err := time.Parse(layout, value); err != nil {
+		return fmt.Errorf("Illegal value for type '%s': '%s'", dt, value)
+	}
+	return nil
 }
 
 type ColumnName string
@@ -120,6 +285,12 @@ type ColumnSpec struct {
 	Name    ColumnName `json:"name"`
 	Type    DataType   `json:"type"`
 	NotNull bool       `json:"not_null"`
+
+	// Null is the sentinel value that represents null for this column
+	// (e.g. "NULL" or `\N` for CSVs produced by Postgres COPY). It defaults
+	// to the empty string, preserving the historical behavior of treating
+	// "" as null.
+	Null string `json:"null,omitempty"`
 }
 
 type ForeignKeyMapping struct {
@@ -2,7 +2,9 @@ package main
 
 import (
 	"fmt"
+	"runtime"
 	"strings"
+	"sync"
 )
 
 type tableChecker struct {
@@ -12,7 +14,50 @@ type tableChecker struct {
 
 type schemaChecker map[TableName]tableChecker
 
-func checkSchemaConsistency(schema []TableSpec) (schemaChecker, error) {
+// pkIndex is the set of per-table primary-key diskSets built while
+// validating parent tables, kept open (rather than closed at the end of
+// validateTable) so that child tables validated in a later level can check
+// their foreign keys against the same on-disk index instead of re-scanning
+// the parent's data. Levels guarantee every table's parents finish before
+// it starts, so reads and writes never race on the same entry.
+type pkIndex struct {
+	mu   sync.Mutex
+	sets map[TableName]*diskSet
+}
+
+func newPKIndex() *pkIndex {
+	return &pkIndex{sets: map[TableName]*diskSet{}}
+}
+
+func (idx *pkIndex) set(table TableName, s *diskSet) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.sets[table] = s
+}
+
+func (idx *pkIndex) get(table TableName) *diskSet {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return idx.sets[table]
+}
+
+func (idx *pkIndex) closeAll() {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for _, s := range idx.sets {
+		s.Close()
+	}
+}
+
+// checkSchemaConsistency checks schema's internal consistency, recording
+// every problem it finds as a SchemaError or FKMissing violation on report
+// instead of stopping at the first one. It always returns a schemaChecker;
+// tables with unresolvable problems (e.g. a duplicate name) simply keep
+// whatever was built for them first.
+func checkSchemaConsistency(
+	schema []TableSpec,
+	report *ValidationReport,
+) schemaChecker {
 	// Schema checks
 	// [x] Table names are unique
 	// [x] Column names are unique
@@ -26,7 +71,12 @@ func checkSchemaConsistency(schema []TableSpec) (schemaChecker, error) {
 	for _, table := range schema {
 		// The table name is unique
 		if _, found := tableCheckers[table.Name]; found {
-			return nil, fmt.Errorf("Table exists: '%s'", table.Name)
+			report.Add(Violation{
+				Kind:    SchemaError,
+				Table:   table.Name,
+				Message: fmt.Sprintf("Table exists: '%s'", table.Name),
+			})
+			continue
 		}
 		tableCheckers[table.Name] = tableChecker{TableSpec: table}
 
@@ -34,11 +84,17 @@ func checkSchemaConsistency(schema []TableSpec) (schemaChecker, error) {
 		columns := map[ColumnName]ColumnSpec{}
 		for _, column := range table.Columns {
 			if _, found := columns[column.Name]; found {
-				return nil, fmt.Errorf(
-					"Column exists: '%s'.'%s'",
-					table.Name,
-					column.Name,
-				)
+				report.Add(Violation{
+					Kind:   SchemaError,
+					Table:  table.Name,
+					Column: column.Name,
+					Message: fmt.Sprintf(
+						"Column exists: '%s'.'%s'",
+						table.Name,
+						column.Name,
+					),
+				})
+				continue
 			}
 			columns[column.Name] = column
 		}
@@ -53,11 +109,16 @@ func checkSchemaConsistency(schema []TableSpec) (schemaChecker, error) {
 					continue OUTER
 				}
 			}
-			return nil, fmt.Errorf(
-				"Primary key column not found: '%s'.'%s'",
-				table.Name,
-				pkey.Name,
-			)
+			report.Add(Violation{
+				Kind:   SchemaError,
+				Table:  table.Name,
+				Column: pkey.Name,
+				Message: fmt.Sprintf(
+					"Primary key column not found: '%s'.'%s'",
+					table.Name,
+					pkey.Name,
+				),
+			})
 		}
 		tableChecker := tableCheckers[table.Name]
 		tableChecker.pkeyCols = pkeyCols
@@ -67,11 +128,17 @@ func checkSchemaConsistency(schema []TableSpec) (schemaChecker, error) {
 		for _, column := range table.UniqueColumns {
 			for cs := &column; cs != nil; cs = cs.Tail {
 				if _, found := columns[cs.Name]; !found {
-					return nil, fmt.Errorf(
-						"Column not found for unique constraint: '%s'.'%s'",
-						table.Name,
-						cs.Name,
-					)
+					report.Add(Violation{
+						Kind:   SchemaError,
+						Table:  table.Name,
+						Column: cs.Name,
+						Message: fmt.Sprintf(
+							"Column not found for unique constraint: "+
+								"'%s'.'%s'",
+							table.Name,
+							cs.Name,
+						),
+					})
 				}
 			}
 		}
@@ -83,41 +150,68 @@ func checkSchemaConsistency(schema []TableSpec) (schemaChecker, error) {
 				mapping,
 				tableCheckers,
 			); err != nil {
-				return nil, err
+				report.Add(Violation{
+					Kind:    FKMissing,
+					Table:   table.Name,
+					Column:  mapping.LocalColumn.Name,
+					Message: err.Error(),
+				})
 			}
 		}
 	}
 
-	return tableCheckers, nil
+	return tableCheckers
 }
 
-type Set map[string]Set
-
-func (s Set) Exists(ss []string) bool {
-	if len(ss) < 1 {
-		return false
-	}
-	next, found := s[ss[0]]
-	return found && next.Exists(ss[1:])
+// Validate checks repo's data against schema, returning a *ValidationReport
+// (which implements error) describing every violation found, or nil if
+// there were none. It is Report without progress reporting or a cap on how
+// many violations accumulate.
+func Validate(repo Repo, schema []TableSpec) error {
+	return reportAsError(Report(repo, schema, noopReporter{}, 0))
 }
 
-func (s Set) Put(ss []string) {
-	if len(ss) < 1 {
-		return
-	}
-	next, found := s[ss[0]]
-	if !found {
-		next = Set{}
-		s[ss[0]] = next
-	}
-	next.Put(ss[1:])
+// ValidateWithReporter is Validate, additionally delivering progress and
+// per-table statistics to reporter.
+func ValidateWithReporter(
+	repo Repo,
+	schema []TableSpec,
+	reporter ValidationReporter,
+) error {
+	return reportAsError(Report(repo, schema, reporter, 0))
 }
 
-func Validate(repo Repo, schema []TableSpec) error {
-	tableCheckers, err := checkSchemaConsistency(schema)
+func reportAsError(report *ValidationReport, err error) error {
 	if err != nil {
 		return err
 	}
+	if len(report.Violations) == 0 {
+		return nil
+	}
+	return report
+}
+
+// Report is the full form of Validate: it accumulates every violation it
+// finds, up to maxViolations (0 means unlimited), into a ValidationReport
+// instead of stopping at the first one, so a dirty CSV can be fixed in one
+// pass instead of fix-rerun-fix-rerun. report.WriteJSON/WriteText then
+// render the result for a CI pipeline or an editor integration. The
+// returned error is non-nil only for operational failures (e.g. a table's
+// file can't be opened); schema and data problems are recorded as
+// violations in the report instead.
+//
+// Tables are validated one topological level of the foreign key graph at a
+// time: the tables within a level run concurrently, bounded by GOMAXPROCS,
+// and every level finishes before the next starts, so tables related by a
+// foreign key are validated in parent-before-child order.
+func Report(
+	repo Repo,
+	schema []TableSpec,
+	reporter ValidationReporter,
+	maxViolations int,
+) (*ValidationReport, error) {
+	report := &ValidationReport{MaxViolations: maxViolations}
+	tableCheckers := checkSchemaConsistency(schema, report)
 
 	// Data checks
 	// [x] Columns exist in data
@@ -129,126 +223,293 @@ func Validate(repo Repo, schema []TableSpec) error {
 	// [x] Column values are properly typed
 	// [x] Not-null columns are null-free
 	// [ ] Unique columns are unique
-	// [ ] Foreign key values exist in remote columns
+	// [x] Foreign key values exist in remote columns
 
+	// Validate each level of the foreign key graph in turn, so parent
+	// tables finish before the tables that reference them; within a level,
+	// tables have no dependency on one another and run concurrently.
+	graph := BuildSchemaGraph(schema)
+	levels, err := graph.Levels(schema)
+	if err != nil {
+		report.Add(Violation{Kind: SchemaError, Message: err.Error()})
+		return report, nil
+	}
+	tablesByName := make(map[TableName]TableSpec, len(schema))
 	for _, table := range schema {
-		if err := repo.WithTable(string(table.Name), func(rows Rows) error {
-			if len(table.Columns) != len(rows.Headers) {
-				return fmt.Errorf(
-					"Mismatched number of schema columns vs data columns "+
-						"in table '%s': %d schema columns vs %d data columns",
-					table.Name,
-					len(table.Columns),
-					len(rows.Headers),
-				)
+		tablesByName[table.Name] = table
+	}
+
+	pkIdx := newPKIndex()
+	defer pkIdx.closeAll()
+
+	workers := runtime.GOMAXPROCS(0)
+	for _, level := range levels {
+		sem := make(chan struct{}, workers)
+		var wg sync.WaitGroup
+		errs := make([]error, len(level))
+
+		for i, tableName := range level {
+			table := tablesByName[tableName]
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(i int, table TableSpec) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				errs[i] = validateTable(repo, table, tableCheckers, reporter, report, pkIdx)
+			}(i, table)
+		}
+		wg.Wait()
+
+		for _, err := range errs {
+			if err != nil {
+				return report, err
 			}
-			for i, column := range table.Columns {
-				if rows.Headers[i] != string(column.Name) {
-					return fmt.Errorf(
-						"Column %d in table '%s' should be '%s', but got '%s'",
+		}
+	}
+	return report, nil
+}
+
+// validateTable validates every row of a single table, recording violations
+// on report and reporting progress and outcome to reporter. The error it
+// returns is reserved for operational failures reading the table itself.
+func validateTable(
+	repo Repo,
+	table TableSpec,
+	tableCheckers schemaChecker,
+	reporter ValidationReporter,
+	report *ValidationReport,
+	pkIdx *pkIndex,
+) error {
+	reporter.TableStarted(table.Name)
+	rowCount := 0
+	err := repo.WithTable(string(table.Name), func(rows Rows) error {
+		headers := rows.Headers()
+		if len(table.Columns) != len(headers) {
+			report.Add(Violation{
+				Kind:  SchemaError,
+				Table: table.Name,
+				Message: fmt.Sprintf(
+					"Mismatched number of schema columns vs data columns: "+
+						"%d schema columns vs %d data columns",
+					len(table.Columns),
+					len(headers),
+				),
+			})
+			// The columns don't even line up, so there's no point trying
+			// to check this table's rows.
+			return nil
+		}
+		for i, column := range table.Columns {
+			if headers[i] != string(column.Name) {
+				report.Add(Violation{
+					Kind:   SchemaError,
+					Table:  table.Name,
+					Column: column.Name,
+					Message: fmt.Sprintf(
+						"Column %d should be '%s', but got '%s'",
 						i,
-						table.Name,
 						column.Name,
-						rows.Headers[i],
-					)
-				}
+						headers[i],
+					),
+				})
 			}
+		}
 
-			var rowChecks []func(row []string) error
-			rowChecks = append(
-				rowChecks,
-				func(row []string) error {
-					if len(row) != len(table.Columns) {
-						return fmt.Errorf(
-							"Wrong number of cells; wanted %d, got %d",
-							len(table.Columns),
-							len(row),
-						)
+		var rowChecks []func(row []string) []Violation
+		rowChecks = append(
+			rowChecks,
+			func(row []string) []Violation {
+				var violations []Violation
+				for i, column := range table.Columns {
+					if row[i] == column.Null {
+						// The value is this column's configured null
+						// sentinel, so it's exempt from type checking.
+						continue
 					}
-					return nil
-				},
-				func(row []string) error {
-					for i, column := range table.Columns {
-						if err := ValidateDataType(
-							column.Type,
-							row[i],
-						); err != nil {
-							return fmt.Errorf(
-								"Type error in column %d:",
-								i,
-								err,
-							)
-						}
+					if err := ValidateDataType(
+						column.Type,
+						row[i],
+					); err != nil {
+						violations = append(violations, Violation{
+							Kind:    TypeError,
+							Table:   table.Name,
+							Column:  column.Name,
+							Value:   row[i],
+							Message: err.Error(),
+						})
 					}
-					return nil
-				},
-			)
-
-			notNullColumns := make([]struct {
-				name  ColumnName
-				colID int
-			}, 0, 10)
-			for i, column := range table.Columns {
-				if column.NotNull {
-					notNullColumns = append(notNullColumns, struct {
-						name  ColumnName
-						colID int
-					}{column.Name, i})
 				}
+				return violations
+			},
+		)
+
+		notNullColumns := make([]struct {
+			name  ColumnName
+			colID int
+			null  string
+		}, 0, 10)
+		for i, column := range table.Columns {
+			if column.NotNull {
+				notNullColumns = append(notNullColumns, struct {
+					name  ColumnName
+					colID int
+					null  string
+				}{column.Name, i, column.Null})
 			}
-			if len(notNullColumns) > 0 {
-				rowChecks = append(rowChecks, func(row []string) error {
-					for _, col := range notNullColumns {
-						if row[col.colID] == "" {
-							return fmt.Errorf(
+		}
+		if len(notNullColumns) > 0 {
+			rowChecks = append(rowChecks, func(row []string) []Violation {
+				var violations []Violation
+				for _, col := range notNullColumns {
+					if row[col.colID] == col.null {
+						violations = append(violations, Violation{
+							Kind:   NullViolation,
+							Table:  table.Name,
+							Column: col.name,
+							Message: fmt.Sprintf(
 								"Null value found in not-null column '%s'",
 								col.name,
-							)
-						}
+							),
+						})
 					}
+				}
+				return violations
+			})
+		}
+
+		var pkSeen *diskSet
+		if table.PrimaryKey != nil {
+			pkSeen = newDiskSet()
+			pkeyCols := tableCheckers[table.Name].pkeyCols
+			rowChecks = append(rowChecks, func(row []string) []Violation {
+				duplicate, err := pkSeen.Put(joinKey(row, pkeyCols))
+				if err != nil {
+					return []Violation{{
+						Kind:    SchemaError,
+						Table:   table.Name,
+						Message: err.Error(),
+					}}
+				}
+				if !duplicate {
 					return nil
+				}
+				values := make([]string, len(pkeyCols))
+				for i, colID := range pkeyCols {
+					values[i] = row[colID]
+				}
+				value := strings.Join(values, ", ")
+				return []Violation{{
+					Kind:  PKDuplicate,
+					Table: table.Name,
+					Value: value,
+					Message: fmt.Sprintf(
+						"Duplicate value found for primary key column: (%s)",
+						value,
+					),
+				}}
+			})
+		}
+
+		// Foreign key values must exist in the referenced table's primary
+		// key. Mappings that checkForeignKey rejects (already reported by
+		// checkSchemaConsistency) are skipped; for the rest, fkm's foreign
+		// column chain is that table's primary key, so the parent's pkSeen
+		// (built while validating it, in an earlier level) is exactly the
+		// index to check against.
+		for _, fkm := range table.ForeignKeys {
+			if checkForeignKey(table, fkm, tableCheckers) != nil {
+				continue
+			}
+			localCols, err := columnIndices(fkm.LocalColumn, headers)
+			if err != nil {
+				report.Add(Violation{
+					Kind:    SchemaError,
+					Table:   table.Name,
+					Column:  fkm.LocalColumn.Name,
+					Message: err.Error(),
 				})
+				continue
 			}
-
-			if table.PrimaryKey != nil {
-				seen := Set{}
-				pkeyCols := tableCheckers[table.Name].pkeyCols
-				buf := make([]string, len(pkeyCols))
-				rowChecks = append(rowChecks, func(row []string) error {
-					for i, colID := range pkeyCols {
-						buf[i] = row[colID]
-					}
-					if seen.Exists(buf) {
-						return fmt.Errorf(
-							"Duplicate value found for primary key column: "+
-								"(%s)",
-							strings.Join(buf, ", "),
-						)
-					}
-					seen.Put(buf)
+			fkm := fkm
+			parentIndex := pkIdx.get(fkm.ForeignTable)
+			rowChecks = append(rowChecks, func(row []string) []Violation {
+				if parentIndex == nil {
+					// The parent table's rows were not fully checked (its
+					// columns didn't match the schema, or the report filled
+					// up), so it left no primary key index; that problem is
+					// already reported, so don't pile on per row.
 					return nil
+				}
+				found, err := parentIndex.Contains(joinKey(row, localCols))
+				if err != nil {
+					return []Violation{{
+						Kind:    SchemaError,
+						Table:   table.Name,
+						Message: err.Error(),
+					}}
+				}
+				if found {
+					return nil
+				}
+				values := make([]string, len(localCols))
+				for i, colID := range localCols {
+					values[i] = row[colID]
+				}
+				value := strings.Join(values, ", ")
+				return []Violation{{
+					Kind:   FKMissing,
+					Table:  table.Name,
+					Column: fkm.LocalColumn.Name,
+					Value:  value,
+					Message: fmt.Sprintf(
+						"Foreign key value (%s) not found in table '%s'",
+						value,
+						fkm.ForeignTable,
+					),
+				}}
+			})
+		}
+
+		for i := 2; rows.Next(); i++ {
+			rowCount++
+			row := rows.Row()
+			if len(row) != len(table.Columns) {
+				report.Add(Violation{
+					Kind:  SchemaError,
+					Table: table.Name,
+					Row:   i,
+					Message: fmt.Sprintf(
+						"Wrong number of cells; wanted %d, got %d",
+						len(table.Columns),
+						len(row),
+					),
 				})
+				reporter.RowValidated(table.Name, i)
+				if report.Full() {
+					return nil
+				}
+				continue
 			}
 
-			for i := 2; rows.Next(); i++ {
-				for _, check := range rowChecks {
-					if err := check(rows.CurrentRow); err != nil {
-						return fmt.Errorf(
-							"Error in table '%s' row %d: %v",
-							table.Name,
-							i,
-							err,
-						)
-					}
+			for _, check := range rowChecks {
+				for _, violation := range check(row) {
+					violation.Row = i
+					report.Add(violation)
 				}
 			}
+			reporter.RowValidated(table.Name, i)
+			if report.Full() {
+				return nil
+			}
+		}
 
-			return nil
-		}); err != nil {
-			return err
+		if pkSeen != nil {
+			pkIdx.set(table.Name, pkSeen)
 		}
-	}
-	return nil
+		return nil
+	})
+	reporter.TableFinished(table.Name, rowCount, err)
+	return err
 }
 
 func findSpecs(column Column, table TableSpec) ([]ColumnSpec, error) {
@@ -297,10 +558,10 @@ func checkForeignKey(
 		return fmt.Errorf(
 			"Foreign key column in table '%s' references a column in table "+
 				"'%s', but '%s' has no primary key. Foreign keys must map to "+
-				"primary key columns. Foreign key column must be the primary "+
-				"key of the foreign table",
+				"primary key columns",
 			table.Name,
 			foreignTable.Name,
+			foreignTable.Name,
 		)
 	}
 
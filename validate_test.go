@@ -0,0 +1,145 @@
+package main
+
+import "testing"
+
+func violationKinds(report *ValidationReport) map[ViolationKind]int {
+	counts := map[ViolationKind]int{}
+	for _, v := range report.Violations {
+		counts[v.Kind]++
+	}
+	return counts
+}
+
+func TestValidateCleanDataHasNoViolations(t *testing.T) {
+	repo := InMemoryRepo{Tables: map[string][][]string{
+		"customers": {
+			{"id", "name"},
+			{"1", "Alice"},
+			{"2", "Bob"},
+		},
+		"orders": {
+			{"id", "customer_id"},
+			{"10", "1"},
+			{"11", "2"},
+		},
+	}}
+
+	if err := Validate(repo, twoTableSchema()); err != nil {
+		t.Fatalf("Validate on clean data returned: %v", err)
+	}
+}
+
+func TestValidateDetectsForeignKeyAcrossLevels(t *testing.T) {
+	repo := InMemoryRepo{Tables: map[string][][]string{
+		"customers": {
+			{"id", "name"},
+			{"1", "Alice"},
+		},
+		"orders": {
+			{"id", "customer_id"},
+			{"10", "1"},
+			{"11", "999"}, // no customer 999
+		},
+	}}
+
+	report, err := Report(repo, twoTableSchema(), noopReporter{}, 0)
+	if err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+
+	counts := violationKinds(report)
+	if counts[FKMissing] != 1 {
+		t.Fatalf("got %d FKMissing violations, want 1 (violations: %v)", counts[FKMissing], report.Violations)
+	}
+}
+
+func TestValidateDetectsPrimaryKeyDuplicate(t *testing.T) {
+	repo := InMemoryRepo{Tables: map[string][][]string{
+		"customers": {
+			{"id", "name"},
+			{"1", "Alice"},
+			{"1", "Alice Again"},
+		},
+		"orders": {
+			{"id", "customer_id"},
+		},
+	}}
+
+	report, err := Report(repo, twoTableSchema(), noopReporter{}, 0)
+	if err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+
+	counts := violationKinds(report)
+	if counts[PKDuplicate] != 1 {
+		t.Fatalf("got %d PKDuplicate violations, want 1 (violations: %v)", counts[PKDuplicate], report.Violations)
+	}
+}
+
+func TestValidateDetectsTypeAndNullViolations(t *testing.T) {
+	repo := InMemoryRepo{Tables: map[string][][]string{
+		"customers": {
+			{"id", "name"},
+			{"not-an-int", ""},
+		},
+		"orders": {
+			{"id", "customer_id"},
+		},
+	}}
+
+	report, err := Report(repo, twoTableSchema(), noopReporter{}, 0)
+	if err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+
+	counts := violationKinds(report)
+	if counts[TypeError] == 0 {
+		t.Errorf("got no TypeError violations, want at least 1 (violations: %v)", report.Violations)
+	}
+	if counts[NullViolation] == 0 {
+		t.Errorf("got no NullViolation violations, want at least 1 (violations: %v)", report.Violations)
+	}
+}
+
+func TestCheckSchemaConsistencyDetectsDuplicateTableAndColumn(t *testing.T) {
+	schema := []TableSpec{
+		{Name: "t", Columns: []ColumnSpec{{Name: "a"}, {Name: "a"}}},
+		{Name: "t", Columns: []ColumnSpec{{Name: "a"}}},
+	}
+	report := &ValidationReport{}
+	checkSchemaConsistency(schema, report)
+
+	counts := violationKinds(report)
+	if counts[SchemaError] < 2 {
+		t.Fatalf("got %d SchemaError violations, want at least 2 (violations: %v)", counts[SchemaError], report.Violations)
+	}
+}
+
+func TestReportRespectsMaxViolations(t *testing.T) {
+	repo := InMemoryRepo{Tables: map[string][][]string{
+		"customers": {
+			{"id", "name"},
+			{"bad1", ""},
+			{"bad2", ""},
+			{"bad3", ""},
+		},
+		"orders": {
+			{"id", "customer_id"},
+		},
+	}}
+
+	// MaxViolations=1: the first bad row alone produces two violations (a
+	// TypeError for "id" and a NullViolation for "name"), so the second of
+	// those is the one Add drops, setting Truncated within a single row
+	// rather than requiring several rows to fill the cap.
+	report, err := Report(repo, twoTableSchema(), noopReporter{}, 1)
+	if err != nil {
+		t.Fatalf("Report: %v", err)
+	}
+	if len(report.Violations) != 1 {
+		t.Fatalf("got %d violations, want exactly 1 (MaxViolations cap)", len(report.Violations))
+	}
+	if !report.Truncated {
+		t.Fatal("report.Truncated = false, want true once MaxViolations is hit")
+	}
+}